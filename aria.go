@@ -0,0 +1,207 @@
+package tvecty
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ariaValueType int
+
+const (
+	ariaString ariaValueType = iota
+	ariaBool
+	ariaBoolOrUndefined
+	ariaTristate
+	ariaIdRef
+	ariaIdRefs
+	ariaInteger
+	ariaNumber
+	ariaToken
+	ariaTokens
+)
+
+type ariaAttribute struct {
+	valueType ariaValueType
+	// Allowed values for token and token list attributes.
+	tokens []string
+}
+
+// The WAI-ARIA 1.2 states and properties and the type of value they accept.
+var ariaAttributes = map[string]ariaAttribute{
+	"aria-activedescendant":       {valueType: ariaIdRef},
+	"aria-atomic":                 {valueType: ariaBool},
+	"aria-autocomplete":           {valueType: ariaToken, tokens: []string{"inline", "list", "both", "none"}},
+	"aria-braillelabel":           {valueType: ariaString},
+	"aria-brailleroledescription": {valueType: ariaString},
+	"aria-busy":                   {valueType: ariaBool},
+	"aria-checked":                {valueType: ariaTristate},
+	"aria-colcount":               {valueType: ariaInteger},
+	"aria-colindex":               {valueType: ariaInteger},
+	"aria-colindextext":           {valueType: ariaString},
+	"aria-colspan":                {valueType: ariaInteger},
+	"aria-controls":               {valueType: ariaIdRefs},
+	"aria-current":                {valueType: ariaToken, tokens: []string{"page", "step", "location", "date", "time", "true", "false"}},
+	"aria-describedby":            {valueType: ariaIdRefs},
+	"aria-description":            {valueType: ariaString},
+	"aria-details":                {valueType: ariaIdRef},
+	"aria-disabled":               {valueType: ariaBool},
+	"aria-dropeffect":             {valueType: ariaTokens, tokens: []string{"copy", "execute", "link", "move", "none", "popup"}},
+	"aria-errormessage":           {valueType: ariaIdRef},
+	"aria-expanded":               {valueType: ariaBoolOrUndefined},
+	"aria-flowto":                 {valueType: ariaIdRefs},
+	"aria-grabbed":                {valueType: ariaBoolOrUndefined},
+	"aria-haspopup":               {valueType: ariaToken, tokens: []string{"false", "true", "menu", "listbox", "tree", "grid", "dialog"}},
+	"aria-hidden":                 {valueType: ariaBoolOrUndefined},
+	"aria-invalid":                {valueType: ariaToken, tokens: []string{"grammar", "false", "spelling", "true"}},
+	"aria-keyshortcuts":           {valueType: ariaString},
+	"aria-label":                  {valueType: ariaString},
+	"aria-labelledby":             {valueType: ariaIdRefs},
+	"aria-level":                  {valueType: ariaInteger},
+	"aria-live":                   {valueType: ariaToken, tokens: []string{"assertive", "off", "polite"}},
+	"aria-modal":                  {valueType: ariaBool},
+	"aria-multiline":              {valueType: ariaBool},
+	"aria-multiselectable":        {valueType: ariaBool},
+	"aria-orientation":            {valueType: ariaToken, tokens: []string{"horizontal", "undefined", "vertical"}},
+	"aria-owns":                   {valueType: ariaIdRefs},
+	"aria-placeholder":            {valueType: ariaString},
+	"aria-posinset":               {valueType: ariaInteger},
+	"aria-pressed":                {valueType: ariaTristate},
+	"aria-readonly":               {valueType: ariaBool},
+	"aria-relevant":               {valueType: ariaTokens, tokens: []string{"additions", "all", "removals", "text"}},
+	"aria-required":               {valueType: ariaBool},
+	"aria-roledescription":        {valueType: ariaString},
+	"aria-rowcount":               {valueType: ariaInteger},
+	"aria-rowindex":               {valueType: ariaInteger},
+	"aria-rowindextext":           {valueType: ariaString},
+	"aria-rowspan":                {valueType: ariaInteger},
+	"aria-selected":               {valueType: ariaBoolOrUndefined},
+	"aria-setsize":                {valueType: ariaInteger},
+	"aria-sort":                   {valueType: ariaToken, tokens: []string{"ascending", "descending", "none", "other"}},
+	"aria-valuemax":               {valueType: ariaNumber},
+	"aria-valuemin":               {valueType: ariaNumber},
+	"aria-valuenow":               {valueType: ariaNumber},
+	"aria-valuetext":              {valueType: ariaString},
+}
+
+// Checks an aria attribute is a known WAI-ARIA attribute and, when its value is not an embed, that the value is valid
+// for the attribute.
+func validateAriaAttribute(name, value string) error {
+	aria, ok := ariaAttributes[name]
+	if !ok {
+		if suggestion := closestAriaAttribute(name); suggestion != "" {
+			return fmt.Errorf("unknown aria attribute '%s', did you mean '%s'?", name, suggestion)
+		}
+		return fmt.Errorf("unknown aria attribute '%s'", name)
+	}
+	parts, err := tokenizeExpressionParts(value)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if part.isEmbeddedCode {
+			return nil
+		}
+	}
+	value = strings.TrimSpace(value)
+	invalid := func(expected string) error {
+		return fmt.Errorf("invalid value '%s' for aria attribute '%s', expected %s", value, name, expected)
+	}
+	switch aria.valueType {
+	case ariaBool:
+		if value != "true" && value != "false" {
+			return invalid("true or false")
+		}
+	case ariaBoolOrUndefined:
+		if value != "true" && value != "false" && value != "undefined" {
+			return invalid("true, false or undefined")
+		}
+	case ariaTristate:
+		if value != "true" && value != "false" && value != "mixed" && value != "undefined" {
+			return invalid("true, false, mixed or undefined")
+		}
+	case ariaIdRef:
+		if value == "" || strings.ContainsAny(value, " \t\n") {
+			return invalid("a single element id")
+		}
+	case ariaIdRefs:
+		if value == "" {
+			return invalid("a space separated list of element ids")
+		}
+	case ariaInteger:
+		if _, err := strconv.Atoi(value); err != nil {
+			return invalid("an integer")
+		}
+	case ariaNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return invalid("a number")
+		}
+	case ariaToken:
+		if !containsString(aria.tokens, value) {
+			return invalid("one of " + strings.Join(aria.tokens, ", "))
+		}
+	case ariaTokens:
+		for _, token := range strings.Fields(value) {
+			if !containsString(aria.tokens, token) {
+				return invalid("a space separated list of " + strings.Join(aria.tokens, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Finds the known aria attribute closest to name, used to suggest corrections for typos. Returns an empty string if
+// there are no close matches.
+func closestAriaAttribute(name string) string {
+	names := make([]string, 0, len(ariaAttributes))
+	for n := range ariaAttributes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	closest := ""
+	closestDistance := 3
+	for _, n := range names {
+		if d := editDistance(name, n); d < closestDistance {
+			closest = n
+			closestDistance = d
+		}
+	}
+	return closest
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(prev[j]+1, current[j-1]+1), prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,12 +5,13 @@ import (
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
 	"go/token"
+	"strconv"
 )
 
 func stringLit(s string) *dst.BasicLit {
 	return &dst.BasicLit{
 		Kind:  token.STRING,
-		Value: `"` + s + `"`,
+		Value: strconv.Quote(s),
 		Decs:  dst.BasicLitDecorations{},
 	}
 }
@@ -42,6 +43,19 @@ func simpleCallExpr(x, sel string, args []dst.Expr) *dst.CallExpr {
 	}
 }
 
+// Calls a method with no arguments on the result of expr, ex. event.Submit(h) -> event.Submit(h).PreventDefault()
+func chainedCallExpr(expr dst.Expr, method string) *dst.CallExpr {
+	decs := *expr.Decorations()
+	*expr.Decorations() = dst.NodeDecs{}
+	return &dst.CallExpr{
+		Decs: dst.CallExprDecorations{NodeDecs: decs},
+		Fun: &dst.SelectorExpr{
+			X:   expr,
+			Sel: dst.NewIdent(method),
+		},
+	}
+}
+
 func parseExpression(exprStr string, addNewLines bool) (dst.Expr, error) {
 	// Basically cheat and make a mini go file for dst to parse.
 	v, err := decorator.Parse(fmt.Sprintf("package tmp; var e = %s", exprStr))
@@ -67,3 +81,43 @@ func parseExpression(exprStr string, addNewLines bool) (dst.Expr, error) {
 	}
 	return expr, nil
 }
+
+// Checks that stmtsStr is a valid list of statements, ex. "c.count++; vecty.Rerender(c)"
+func checkStatements(stmtsStr string) error {
+	_, err := decorator.Parse(fmt.Sprintf("package tmp; func _() {\n%s\n}", stmtsStr))
+	if err != nil {
+		return fmt.Errorf("error with statements '%s':\n%w", stmtsStr, err)
+	}
+	return nil
+}
+
+// Adds an import to the file if it is not already imported.
+func addImport(f *dst.File, path string) {
+	quoted := strconv.Quote(path)
+	var importDecl *dst.GenDecl
+	for _, d := range f.Decls {
+		gd, ok := d.(*dst.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if spec.(*dst.ImportSpec).Path.Value == quoted {
+				return
+			}
+		}
+		if importDecl == nil {
+			importDecl = gd
+		}
+	}
+	spec := &dst.ImportSpec{Path: &dst.BasicLit{Kind: token.STRING, Value: quoted}}
+	if importDecl == nil {
+		importDecl = &dst.GenDecl{Tok: token.IMPORT}
+		f.Decls = append([]dst.Decl{importDecl}, f.Decls...)
+	}
+	if len(importDecl.Specs) > 0 {
+		importDecl.Lparen = true
+		importDecl.Specs[len(importDecl.Specs)-1].Decorations().After = dst.NewLine
+		spec.Decs.Before = dst.NewLine
+	}
+	importDecl.Specs = append(importDecl.Specs, spec)
+}
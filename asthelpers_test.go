@@ -0,0 +1,27 @@
+package tvecty
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStringLit_EscapesQuotesAndBackslashes(t *testing.T) {
+	require.Equal(t, `"say \"hi\""`, stringLit(`say "hi"`).Value)
+	require.Equal(t, `"C:\\dir"`, stringLit(`C:\dir`).Value)
+}
+
+func TestHtmlToDst_TextWithQuotesIsAValidString(t *testing.T) {
+	expr, err := htmlToDst(`<p title='a "b"'>say "hi"</p>`)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Paragraph(
+		vecty.Markup(
+			vecty.Attribute("title", "a \"b\""),
+		),
+		vecty.Text("say \"hi\""),
+	)
+}`)
+}
@@ -0,0 +1,136 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"strings"
+)
+
+// A two-way binding between a property of a form control and a Go value.
+type binding struct {
+	property string
+	// Converts the js property value into the Go value, the format receives the js property value and the target
+	// field.
+	assignFormat string
+	// Import required by the conversion, if any.
+	importPath string
+}
+
+var bindings = map[string]binding{
+	"value": {
+		property:     "value",
+		assignFormat: "%[2]s = e.Target.Get(%[1]q).String()",
+	},
+	"value.int": {
+		property:     "value",
+		assignFormat: "if v, err := strconv.Atoi(e.Target.Get(%[1]q).String()); err == nil {\n%[2]s = v\n}",
+		importPath:   "strconv",
+	},
+	"value.float": {
+		property:     "value",
+		assignFormat: "if v, err := strconv.ParseFloat(e.Target.Get(%[1]q).String(), 64); err == nil {\n%[2]s = v\n}",
+		importPath:   "strconv",
+	},
+	"checked": {
+		property:     "checked",
+		assignFormat: "%[2]s = e.Target.Get(%[1]q).Bool()",
+	},
+}
+
+// Parse a binding attribute into the property that displays the value and the event handler that writes changes
+// back. Ex. bind:value={&c.Name} sets the value property to c.Name and updates c.Name on each input event, calling
+// vecty.Rerender(c). The value is converted to a string, int (bind:value.int), float64 (bind:value.float) or bool
+// (bind:checked).
+func parseBindAttribute(ctx *tagContext, tag *html.TagOrText, attr *html.Attr) ([]dst.Expr, error) {
+	bindName := strings.TrimPrefix(attr.Name, "bind:")
+	b, ok := bindings[bindName]
+	if !ok {
+		return nil, fmt.Errorf("unknown binding '%s'", attr.Name)
+	}
+	eventName := "change"
+	switch tag.TagName {
+	case "input":
+		if b.property == "value" {
+			eventName = "input"
+		}
+	case "textarea":
+		eventName = "input"
+		fallthrough
+	case "select":
+		if b.property != "value" {
+			return nil, fmt.Errorf("binding '%s' cannot be used on <%s>", attr.Name, tag.TagName)
+		}
+	default:
+		return nil, fmt.Errorf("binding '%s' can only be used on <input>, <textarea> and <select>, but was used on <%s>", attr.Name, tag.TagName)
+	}
+
+	pointerStr, err := eventHandlerValue(attr.Name, attr.Value)
+	if err != nil {
+		return nil, err
+	}
+	target, err := bindTarget(pointerStr)
+	if err != nil {
+		return nil, fmt.Errorf("binding '%s' must be a pointer expression: %w", attr.Name, err)
+	}
+	if b.importPath != "" {
+		ctx.requireImport(b.importPath)
+	}
+
+	property, err := parseExpression(target, false)
+	if err != nil {
+		return nil, err
+	}
+	// The component is rerendered after the value is written, so the value must be reached from a variable holding
+	// the component, ex. c in &c.Name, rather than a package, ex. &settings.Name, or a bare pointer.
+	component := rootIdent(property)
+	if component == "" || ctx.packages[component] {
+		return nil, fmt.Errorf("binding '%s' must point to a field of the component so it can be rerendered, ex. {&c.Name}, but was '%s'", attr.Name, pointerStr)
+	}
+	body := fmt.Sprintf(b.assignFormat, b.property, target) + fmt.Sprintf("\nvecty.Rerender(%s)", component)
+	handler, err := parseExpression(wrapEventHandlerBody(body, nil), false)
+	if err != nil {
+		return nil, err
+	}
+	return []dst.Expr{
+		simpleCallExpr("vecty", "Property", []dst.Expr{stringLit(b.property), property}),
+		simpleCallExpr("event", eventTranslations[eventName], []dst.Expr{handler}),
+	}, nil
+}
+
+// Get the value a binding pointer points to, ex. &c.Name -> c.Name and ptr -> *ptr.
+func bindTarget(pointerStr string) (string, error) {
+	pointer, err := parseExpression(pointerStr, false)
+	if err != nil {
+		return "", err
+	}
+	if u, ok := pointer.(*dst.UnaryExpr); ok && u.Op == token.AND {
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(pointerStr), "&")), nil
+	}
+	switch pointer.(type) {
+	case *dst.Ident, *dst.SelectorExpr, *dst.IndexExpr, *dst.CallExpr:
+		return "*" + pointerStr, nil
+	}
+	return "*(" + pointerStr + ")", nil
+}
+
+// Get the identifier at the root of a field selector, ex. c.user.Name -> c and c.items[i].Name -> c. Returns an empty
+// string when the expression is not a field selector.
+func rootIdent(expr dst.Expr) string {
+	if _, ok := expr.(*dst.SelectorExpr); !ok {
+		return ""
+	}
+	for {
+		switch x := expr.(type) {
+		case *dst.Ident:
+			return x.Name
+		case *dst.SelectorExpr:
+			expr = x.X
+		case *dst.IndexExpr:
+			expr = x.X
+		default:
+			return ""
+		}
+	}
+}
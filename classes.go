@@ -0,0 +1,96 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"strings"
+)
+
+// Collects the conditional classes of an element, from class:name={cond} attributes and class map embeds
+// (ex. class="{{"active": on}}"), into a single vecty.ClassMap.
+type classMapBuilder struct {
+	classMap *dst.CompositeLit
+	names    map[string]bool
+}
+
+// Parse a class attribute value into the static classes, ex. "btn {c.size}", and the class maps in it,
+// ex. {{"active": on}}. The class maps are added to the builder.
+func (b *classMapBuilder) parseClassAttribute(ctx *tagContext, attr *html.Attr) ([]dst.Expr, error) {
+	parts, err := tokenizeExpressionParts(attr.Value)
+	if err != nil {
+		return nil, err
+	}
+	var classParts []embedToken
+	for _, part := range parts {
+		v := strings.TrimSpace(part.value)
+		if !part.isEmbeddedCode || !strings.HasPrefix(v, "{") {
+			classParts = append(classParts, part)
+			continue
+		}
+		expr, err := parseExpression("vecty.ClassMap"+v, false)
+		if err != nil {
+			return nil, err
+		}
+		lit, ok := expr.(*dst.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("invalid class map '%s'", v)
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*dst.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("class map '%s' must contain key value pairs", v)
+			}
+			if err := b.add(kv.Key, kv.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(classParts) == 0 {
+		return nil, nil
+	}
+	return parseMultipleAttributeTokens(ctx, nil, classParts, false)
+}
+
+// Add a class:name={cond} attribute to the builder.
+func (b *classMapBuilder) parseClassToggle(attr *html.Attr) error {
+	name := strings.TrimPrefix(attr.RawName, "class:")
+	if name == "" {
+		return fmt.Errorf("missing class name in attribute '%s'", attr.Name)
+	}
+	if strings.TrimSpace(attr.Value) == "" {
+		return fmt.Errorf("class toggle '%s' requires a condition, ex. %s={cond}", attr.Name, attr.Name)
+	}
+	cond, err := eventHandlerValue(attr.Name, attr.Value)
+	if err != nil {
+		return err
+	}
+	condExpr, err := parseExpression(cond, false)
+	if err != nil {
+		return err
+	}
+	return b.add(stringLit(name), condExpr)
+}
+
+func (b *classMapBuilder) add(key, cond dst.Expr) error {
+	if lit, ok := key.(*dst.BasicLit); ok {
+		if b.names[lit.Value] {
+			return fmt.Errorf("duplicate conditional class %s", lit.Value)
+		}
+		if b.names == nil {
+			b.names = map[string]bool{}
+		}
+		b.names[lit.Value] = true
+	}
+	kv := &dst.KeyValueExpr{Key: key, Value: cond}
+	kv.Decs.Before = dst.NewLine
+	kv.Decs.After = dst.NewLine
+	if b.classMap == nil {
+		b.classMap = &dst.CompositeLit{
+			Type: &dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("ClassMap")},
+			Decs: dst.CompositeLitDecorations{NodeDecs: dst.NodeDecs{Before: dst.NewLine, After: dst.NewLine}},
+		}
+	}
+	b.classMap.Elts = append(b.classMap.Elts, kv)
+	return nil
+}
@@ -0,0 +1,179 @@
+package tvecty
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mdev5000/tvecty/html"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClassManifest is the set of class names templates can add to elements, for tools that remove unused css.
+type ClassManifest struct {
+	classes map[string]bool
+	// Dynamic are the class embeds whose class names are only known at runtime.
+	Dynamic []DynamicClass
+}
+
+// DynamicClass is a class embed whose class names can't be determined from the template, ex. class="btn {c.size}".
+type DynamicClass struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Expr string `json:"expr"`
+}
+
+func (d DynamicClass) String() string {
+	return fmt.Sprintf("%s:%d: dynamic class '{%s}'", d.File, d.Line, d.Expr)
+}
+
+func NewClassManifest() *ClassManifest {
+	return &ClassManifest{classes: map[string]bool{}}
+}
+
+// Classes gets the static class names in sorted order.
+func (m *ClassManifest) Classes() []string {
+	out := make([]string, 0, len(m.classes))
+	for class := range m.classes {
+		out = append(out, class)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Extract adds the classes of the templates in the source to the manifest. Classes come from class attributes,
+// including the keys of class maps, class:name toggles and conditional class attributes, and the class scoping the
+// <style scoped> elements of the file.
+func (m *ClassManifest) Extract(filename string, src []byte) error {
+	tags, err := ExtractHtml(filename, io.Discard, src)
+	if err != nil {
+		return err
+	}
+	scopeClass, _, err := collectScopedStyles(filename, tags)
+	if err != nil {
+		return err
+	}
+	m.add(scopeClass)
+	for _, tag := range tags {
+		if err := m.extractTag(filename, tag); err != nil {
+			return &PositionError{Filename: filename, Line: tag.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+func (m *ClassManifest) extractTag(filename string, tag *html.TagOrText) error {
+	if tag.IsComment {
+		return nil
+	}
+	nested, err := nestedTemplates(tag)
+	if err != nil {
+		return err
+	}
+	for _, child := range nested {
+		if err := m.extractTag(filename, child); err != nil {
+			return err
+		}
+	}
+	if tag.TagName == "" {
+		return nil
+	}
+	for _, attr := range tag.Attr {
+		var err error
+		switch {
+		case attr.Name == "class":
+			err = m.extractClassValue(filename, tag.Line, attr.Value)
+		case attr.Name == "class?":
+			// Conditional classes, ex. class?='{c.active, "active selected"}'
+			parts := splitTopLevel(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(attr.Value), "{"), "}"), ',')
+			if len(parts) == 2 {
+				m.addClassExpr(filename, tag.Line, strings.TrimSpace(parts[1]))
+			}
+		case strings.HasPrefix(attr.Name, "class:"):
+			m.add(strings.TrimPrefix(attr.RawName, "class:"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range tag.Children {
+		if err := m.extractTag(filename, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ClassManifest) extractClassValue(filename string, line int, value string) error {
+	parts, err := tokenizeExpressionParts(value)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		v := strings.TrimSpace(part.value)
+		switch {
+		case !part.isEmbeddedCode:
+			m.add(strings.Fields(v)...)
+		case strings.HasPrefix(v, "{"):
+			src := "map[string]bool" + v
+			expr, err := parser.ParseExpr(src)
+			if err != nil {
+				return fmt.Errorf("invalid class map '%s': %w", v, err)
+			}
+			lit, ok := expr.(*ast.CompositeLit)
+			if !ok {
+				return fmt.Errorf("invalid class map '%s'", v)
+			}
+			for _, elt := range lit.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					// Positions of a parsed expression are offsets from 1.
+					m.addClassExpr(filename, line, src[kv.Key.Pos()-1:kv.Key.End()-1])
+				}
+			}
+		default:
+			m.addClassExpr(filename, line, v)
+		}
+	}
+	return nil
+}
+
+// Adds the classes of an expression if it's a string literal, otherwise it's recorded as dynamic.
+func (m *ClassManifest) addClassExpr(filename string, line int, exprStr string) {
+	expr, err := parser.ParseExpr(exprStr)
+	if lit, ok := expr.(*ast.BasicLit); ok && err == nil && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			m.add(strings.Fields(s)...)
+			return
+		}
+	}
+	m.Dynamic = append(m.Dynamic, DynamicClass{File: filename, Line: line, Expr: exprStr})
+}
+
+func (m *ClassManifest) add(classes ...string) {
+	for _, class := range classes {
+		if class != "" {
+			m.classes[class] = true
+		}
+	}
+}
+
+// WriteJSON writes the manifest as JSON.
+func (m *ClassManifest) WriteJSON(w io.Writer) error {
+	dynamic := m.Dynamic
+	if dynamic == nil {
+		dynamic = []DynamicClass{}
+	}
+	b, err := json.MarshalIndent(struct {
+		Classes []string       `json:"classes"`
+		Dynamic []DynamicClass `json:"dynamic"`
+	}{m.Classes(), dynamic}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
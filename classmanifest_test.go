@@ -0,0 +1,77 @@
+package tvecty
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestClassManifest_ExtractsStaticClasses(t *testing.T) {
+	m := NewClassManifest()
+	require.NoError(t, m.Extract("comp.vtpl", []byte(`package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div class="card  p-4" class:is-open={c.open}>
+		<p class='text-sm {{"font-bold": c.bold, c.extra: true}} {c.size}'>Hi</p>
+		<span class?='{c.active, "ring ring-blue"}'></span>
+		<b class?="{c.active, c.activeClass}"></b>
+	</div>
+}
+`)))
+	require.Equal(t, []string{"card", "font-bold", "is-open", "p-4", "ring", "ring-blue", "text-sm"}, m.Classes())
+	require.Equal(t, []DynamicClass{
+		{File: "comp.vtpl", Line: 5, Expr: "c.extra"},
+		{File: "comp.vtpl", Line: 5, Expr: "c.size"},
+		{File: "comp.vtpl", Line: 7, Expr: "c.activeClass"},
+	}, m.Dynamic)
+}
+
+func TestClassManifest_WritesJSON(t *testing.T) {
+	m := NewClassManifest()
+	require.NoError(t, m.Extract("comp.vtpl", []byte("package comps\n\nvar x = <p class=\"b a\">Hi</p>\n")))
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, m.WriteJSON(out))
+	requireEqStr(t, out.String(), `
+{
+  "classes": [
+    "a",
+    "b"
+  ],
+  "dynamic": []
+}`)
+}
+
+func TestClassManifest_ExtractsClassesOfNestedTemplates(t *testing.T) {
+	m := NewClassManifest()
+	require.NoError(t, m.Extract("comp.vtpl", []byte(`package comps
+
+var x = <ul>{c.ok && <li class="item">{c.name}</li>}</ul>
+`)))
+	require.Equal(t, []string{"item"}, m.Classes())
+}
+
+func TestClassManifest_ExtractsTheScopedStyleClass(t *testing.T) {
+	src := []byte(`package comps
+
+var x = <div><style scoped>.a { color: red; }</style><p class="a">Hi</p></div>
+`)
+	tags, err := ExtractHtml("comp.vtpl", io.Discard, src)
+	require.NoError(t, err)
+	scopeClass, _, err := collectScopedStyles("comp.vtpl", tags)
+	require.NoError(t, err)
+	require.Regexp(t, `^tv-[0-9a-f]{8}$`, scopeClass)
+
+	m := NewClassManifest()
+	require.NoError(t, m.Extract("comp.vtpl", src))
+	require.Equal(t, []string{"a", scopeClass}, m.Classes())
+}
+
+func TestClassManifest_ExtractsClassesOfTemplatesNestedInAttributes(t *testing.T) {
+	m := NewClassManifest()
+	require.NoError(t, m.Extract("comp.vtpl", []byte(`package comps
+
+var x = <my-list renderItem={func(i Item) vecty.ComponentOrHTML { return <li class="item"/> }}></my-list>
+`)))
+	require.Equal(t, []string{"item"}, m.Classes())
+}
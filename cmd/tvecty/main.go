@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/mdev5000/globerous"
 	"github.com/mdev5000/tvecty"
@@ -20,14 +21,19 @@ func main() {
 }
 
 func run() error {
+	var opts compilerOptions
 	compile := &cobra.Command{
 		Use:     "compile",
 		Short:   "",
 		Aliases: []string{"c"},
 	}
+	compile.PersistentFlags().StringVar(&opts.configPath, "config", "", "Configuration file describing custom elements.")
+	compile.PersistentFlags().BoolVar(&opts.noUnsafeHTML, "no-unsafe-html", false, "Make any use of {unsafe:...} an error.")
+	compile.PersistentFlags().BoolVar(&opts.stripComments, "strip-comments", false, "Leave html comments out of the generated code.")
+	compile.PersistentFlags().BoolVar(&opts.typeCheck, "typecheck", false, "Type check the package to wrap embeds based on their type.")
 	compile.AddCommand(
-		cmdCompileFile(),
-		cmdCompileDir(),
+		cmdCompileFile(&opts),
+		cmdCompileDir(&opts),
 	)
 
 	rootCmd := &cobra.Command{
@@ -35,12 +41,18 @@ func run() error {
 		Short: "Generate vecty code from templates",
 		Args:  cobra.MinimumNArgs(1),
 	}
-	rootCmd.AddCommand(compile)
+	i18n := &cobra.Command{
+		Use:   "i18n",
+		Short: "Internationalization tools",
+	}
+	i18n.AddCommand(cmdI18nExtract())
+
+	rootCmd.AddCommand(compile, i18n, cmdClasses())
 
 	return rootCmd.Execute()
 }
 
-func cmdCompileDir() *cobra.Command {
+func cmdCompileDir(opts *compilerOptions) *cobra.Command {
 	var outSuffix string
 	compileFile := cobra.Command{
 		Use: "dir [*file-glob]",
@@ -59,8 +71,20 @@ func cmdCompileDir() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			compiler, err := newCompiler(opts)
+			if err != nil {
+				return err
+			}
 			for _, f := range filesToCompile {
-				if err := compileFileToPath(f, f+outSuffix); err != nil {
+				src, err := os.ReadFile(f)
+				if err != nil {
+					return err
+				}
+				// Partials are compiled as part of the templates that include them.
+				if tvecty.IsPartial(src) {
+					continue
+				}
+				if err := compileFileToPath(compiler, f, f+outSuffix, false); err != nil {
 					return err
 				}
 			}
@@ -71,28 +95,167 @@ func cmdCompileDir() *cobra.Command {
 	return &compileFile
 }
 
-func cmdCompileFile() *cobra.Command {
+func cmdCompileFile(opts *compilerOptions) *cobra.Command {
 	var noHtml bool
 	compileFile := cobra.Command{
 		Use:     "file [*file-in] [file-out]",
 		Aliases: []string{"f"},
 		Args:    cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var out io.Writer
+			compiler, err := newCompiler(opts)
+			if err != nil {
+				return err
+			}
 			if len(args) > 1 {
-				out, err := openFile(args[1])
+				return compileFileToPath(compiler, args[0], args[1], noHtml)
+			}
+			// Scoped styles are written next to the input file when writing to stdout.
+			return compileFile(compiler, args[0], "", os.Stdout, cssOutputPath(args[0]), noHtml)
+		},
+	}
+	compileFile.Flags().BoolVar(&noHtml, "no-html", false, "Do not convert html (useful for debugging).")
+	return &compileFile
+}
+
+func cmdI18nExtract() *cobra.Command {
+	var format, outPath string
+	extract := cobra.Command{
+		Use:   "extract [*file-glob]",
+		Short: "Extract the text of templates into a message catalog",
+		Example: `
+  tvecty i18n extract **/*.vtpl > messages.pot
+  tvecty i18n extract --format json --out messages.json **/*.vtpl`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "pot" && format != "json" {
+				return fmt.Errorf("invalid format '%s', must be pot or json", format)
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			files, err := matchFiles(wd, args[0], "file-glob")
+			if err != nil {
+				return err
+			}
+			catalog := tvecty.NewCatalog()
+			for _, f := range files {
+				src, err := os.ReadFile(f)
+				if err != nil {
+					return err
+				}
+				// Reference files relative to the working directory, so the catalog is the same on every machine.
+				relPath, err := filepath.Rel(wd, f)
+				if err != nil {
+					return err
+				}
+				if err := catalog.Extract(filepath.ToSlash(relPath), src); err != nil {
+					return err
+				}
+			}
+			var out io.Writer = os.Stdout
+			if outPath != "" {
+				f, err := openFile(outPath)
 				if err != nil {
 					return err
 				}
-				defer out.Close()
-			} else {
-				out = os.Stdout
+				defer f.Close()
+				out = f
+			}
+			if format == "json" {
+				return catalog.WriteJSON(out)
 			}
-			return compileFile(args[0], out, noHtml)
+			return catalog.WritePOT(out)
 		},
 	}
-	compileFile.Flags().BoolVar(&noHtml, "no-html", false, "Do not convert html (useful for debugging).")
-	return &compileFile
+	extract.Flags().StringVar(&format, "format", "pot", "Format of the catalog, pot or json.")
+	extract.Flags().StringVarP(&outPath, "out", "o", "", "File to write the catalog to, defaults to stdout.")
+	return &extract
+}
+
+func cmdClasses() *cobra.Command {
+	var jsonOut bool
+	var ext, outPath string
+	classes := cobra.Command{
+		Use:   "classes [*pattern]",
+		Short: "List the static class names used by templates",
+		Long: `List the static class names used by templates, one per line. Class embeds that can't be resolved are
+reported on stderr, or in the "dynamic" list of the JSON output.`,
+		Example: `
+  tvecty classes ./...
+  tvecty classes --json --out classes.json ./ui/...
+  tvecty classes ui/**/*.vtpl`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			files, err := templateFiles(wd, args[0], ext)
+			if err != nil {
+				return err
+			}
+			manifest := tvecty.NewClassManifest()
+			for _, f := range files {
+				src, err := os.ReadFile(f)
+				if err != nil {
+					return err
+				}
+				relPath, err := filepath.Rel(wd, f)
+				if err != nil {
+					return err
+				}
+				if err := manifest.Extract(filepath.ToSlash(relPath), src); err != nil {
+					return err
+				}
+			}
+			var out io.Writer = os.Stdout
+			if outPath != "" {
+				f, err := openFile(outPath)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			if jsonOut {
+				return manifest.WriteJSON(out)
+			}
+			for _, d := range manifest.Dynamic {
+				fmt.Fprintln(os.Stderr, d)
+			}
+			for _, class := range manifest.Classes() {
+				if _, err := fmt.Fprintln(out, class); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	classes.Flags().BoolVar(&jsonOut, "json", false, "Write the classes as JSON.")
+	classes.Flags().StringVar(&ext, "ext", ".vtpl", "Extension of the template files when using a ./... pattern.")
+	classes.Flags().StringVarP(&outPath, "out", "o", "", "File to write the classes to, defaults to stdout.")
+	return &classes
+}
+
+// Gets the template files matching the pattern, which is either a Go style package pattern, ex. ./ui/..., matching
+// all files with the extension in the directory and its sub directories, or a file glob.
+func templateFiles(wd, pattern, ext string) ([]string, error) {
+	if pattern != "..." && !strings.HasSuffix(pattern, "/...") {
+		return matchFiles(wd, pattern, "pattern")
+	}
+	root := filepath.Join(wd, strings.TrimSuffix(pattern, "..."))
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ext) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
 }
 
 func matchFiles(dir, glob, debugName string) ([]string, error) {
@@ -112,20 +275,48 @@ func matchFiles(dir, glob, debugName string) ([]string, error) {
 	return filesToCompile, err
 }
 
-func compileFileToPath(fPathIn, fPathOut string) error {
-	out, err := openFile(fPathOut)
-	if err != nil {
+type compilerOptions struct {
+	configPath    string
+	noUnsafeHTML  bool
+	typeCheck     bool
+	stripComments bool
+}
+
+func newCompiler(opts *compilerOptions) (*tvecty.Compiler, error) {
+	compiler := tvecty.NewCompiler()
+	compiler.DisallowUnsafeHTML = opts.noUnsafeHTML
+	compiler.TypeCheck = opts.typeCheck
+	compiler.StripComments = opts.stripComments
+	if opts.configPath == "" {
+		return compiler, nil
+	}
+	if err := compiler.LoadConfig(opts.configPath); err != nil {
+		return nil, err
+	}
+	return compiler, nil
+}
+
+// Compiles the file to fPathOut. The output is only written once the file has compiled, so a failed compile leaves
+// the previous output as is.
+func compileFileToPath(compiler *tvecty.Compiler, fPathIn, fPathOut string, noHtml bool) error {
+	out := bytes.NewBuffer(nil)
+	if err := compileFile(compiler, fPathIn, fPathOut, out, cssOutputPath(fPathOut), noHtml); err != nil {
 		return err
 	}
-	defer out.Close()
-	return compileFile(fPathIn, out, false)
+	return os.WriteFile(fPathOut, out.Bytes(), 0664)
+}
+
+// Gets the path scoped styles are written to, ex. comp.vtpl.go -> comp.vtpl.css
+func cssOutputPath(path string) string {
+	return strings.TrimSuffix(path, ".go") + ".css"
 }
 
 func openFile(path string) (*os.File, error) {
 	return os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0664)
 }
 
-func compileFile(fPathIn string, out io.Writer, noHtml bool) error {
+// Compiles the file to out, fPathOut is the path out is written to or empty when it's not written to a file.
+func compileFile(compiler *tvecty.Compiler, fPathIn, fPathOut string, out io.Writer, cssPath string, noHtml bool) error {
 	in, err := os.ReadFile(fPathIn)
 	if err != nil {
 		return err
@@ -135,9 +326,24 @@ func compileFile(fPathIn string, out io.Writer, noHtml bool) error {
 			return err
 		}
 	} else {
-		if err := tvecty.ConvertToVecty(fPathIn, out, in); err != nil {
+		css := bytes.NewBuffer(nil)
+		if fPathOut == "" {
+			err = compiler.ConvertToVectyWithStyles(fPathIn, out, css, in)
+		} else {
+			err = compiler.ConvertToVectyFile(fPathIn, fPathOut, out, css, in)
+		}
+		if err != nil {
 			return err
 		}
+		if css.Len() > 0 {
+			return os.WriteFile(cssPath, css.Bytes(), 0664)
+		}
+		if fPathOut != "" {
+			// Remove the css of a previous compile, so styles that are no longer in the template are not shipped.
+			if err := os.Remove(cssPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,150 @@
+package tvecty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dave/dst/decorator"
+	"io"
+	"os"
+	"strings"
+)
+
+// Compiler converts Go source containing html templates into vecty code. Use NewCompiler to create one, the zero
+// value is not usable.
+type Compiler struct {
+	// DisallowUnsafeHTML makes any use of the {unsafe:...} embed modifier an error.
+	DisallowUnsafeHTML bool
+	// TypeCheck wraps embeds in the content of elements based on their type, ex. {name} becomes vecty.Text(name) when
+	// name is a string. The package of the file being converted is type checked, so it must be on disk.
+	TypeCheck bool
+	// StripComments leaves html comments out of the generated code, by default they are kept as Go comments.
+	StripComments bool
+	// Translation replaces the static text of templates with calls to a translation function, nil to leave the text
+	// as is.
+	Translation *Translation
+	elements    map[string]*CustomElement
+	modifiers   map[string]*Modifier
+}
+
+// Config is the tvecty configuration file format, ex.
+//
+//	{
+//	  "elements": [
+//	    {"tag": "sl-button", "properties": ["variant"], "events": ["sl-click"]}
+//	  ],
+//	  "modifiers": [
+//	    {"name": "money", "func": "format.Money", "import": "example.com/app/format"}
+//	  ],
+//	  "translate": {"func": "i18n.T", "import": "example.com/app/i18n"}
+//	}
+type Config struct {
+	Elements  []CustomElement `json:"elements"`
+	Modifiers []Modifier      `json:"modifiers"`
+	Translate *Translation    `json:"translate"`
+}
+
+func NewCompiler() *Compiler {
+	return &Compiler{
+		elements:  map[string]*CustomElement{},
+		modifiers: map[string]*Modifier{},
+	}
+}
+
+// LoadConfig reads a configuration file and applies it to the compiler.
+func (c *Compiler) LoadConfig(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+	return c.ApplyConfig(&cfg)
+}
+
+// ApplyConfig registers everything in the configuration with the compiler.
+func (c *Compiler) ApplyConfig(cfg *Config) error {
+	for _, el := range cfg.Elements {
+		if err := c.RegisterElement(el); err != nil {
+			return err
+		}
+	}
+	if cfg.Translate != nil {
+		if cfg.Translate.Func == "" {
+			return fmt.Errorf("translate is missing the translation function")
+		}
+		c.Translation = cfg.Translate
+	}
+	for _, m := range cfg.Modifiers {
+		if err := c.RegisterModifier(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) ConvertToVecty(filename string, w io.Writer, src []byte) error {
+	return c.ConvertToVectyWithStyles(filename, w, nil, src)
+}
+
+// ConvertToVectyWithStyles converts the source like ConvertToVecty, and writes the css of any <style scoped> elements
+// to css. It's an error for the source to contain scoped styles when css is nil.
+func (c *Compiler) ConvertToVectyWithStyles(filename string, w, css io.Writer, src []byte) error {
+	goFilename := filename
+	if !strings.HasSuffix(goFilename, ".go") {
+		goFilename += ".go"
+	}
+	return c.ConvertToVectyFile(filename, goFilename, w, css, src)
+}
+
+// ConvertToVectyFile converts the source like ConvertToVectyWithStyles, where goFilename is the path the output is
+// written to. The other methods assume the output is written to filename + ".go", which only matters when type
+// checking, since the output is checked in place of the file at that path.
+func (c *Compiler) ConvertToVectyFile(filename, goFilename string, w, css io.Writer, src []byte) error {
+	srcWithoutHtml := bytes.NewBuffer(nil)
+	tracker, err := sourceHtmlReplace(newHtmlTracker(), srcWithoutHtml, bytes.NewReader(src))
+	if err != nil {
+		return err
+	}
+	scopeClass, scopedCSS, err := collectScopedStyles(filename, tracker)
+	if err != nil {
+		return err
+	}
+	if scopedCSS != "" {
+		if css == nil {
+			return fmt.Errorf("'%s' contains scoped styles, but there is no css output", filename)
+		}
+		if _, err := io.WriteString(css, scopedCSS); err != nil {
+			return err
+		}
+	}
+	f, err := decorator.Parse(srcWithoutHtml)
+	if err != nil {
+		return err
+	}
+	ctx := newTagContext(f)
+	ctx.filename = filename
+	ctx.elements = c.elements
+	ctx.modifiers = c.modifiers
+	ctx.disallowUnsafeHTML = c.DisallowUnsafeHTML
+	ctx.typeCheck = c.TypeCheck
+	ctx.translation = c.Translation
+	ctx.scopeClass = scopeClass
+	ctx.stripComments = c.StripComments
+	parsed, err := tracker.parseAll(ctx)
+	if err != nil {
+		return err
+	}
+	if err := Replace(parsed, f); err != nil {
+		return err
+	}
+	ctx.addImports(f)
+	if c.TypeCheck {
+		if err := typeCheckEmbeds(ctx, filename, goFilename, f); err != nil {
+			return err
+		}
+	}
+	return decorator.Fprint(w, f)
+}
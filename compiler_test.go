@@ -0,0 +1,350 @@
+package tvecty
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func shoelaceCompiler(t *testing.T) *Compiler {
+	c := NewCompiler()
+	require.NoError(t, c.RegisterElement(CustomElement{
+		Tag:        "sl-button",
+		Properties: []string{"variant", "disabled", "pill"},
+		Attributes: []string{"href"},
+		Events:     []string{"sl-click", "sl-blur"},
+	}))
+	return c
+}
+
+func compileRender(t *testing.T, c *Compiler, html string) (string, error) {
+	in := `package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return ` + html + `
+}
+`
+	out := bytes.NewBuffer(nil)
+	err := c.ConvertToVecty("comp.vtpl", out, []byte(in))
+	return out.String(), err
+}
+
+func TestCompiler_CustomElementPropertiesAndEvents(t *testing.T) {
+	out, err := compileRender(t, shoelaceCompiler(t),
+		`<sl-button variant="primary" pill href="/home" on:sl-click={c.onClick}>Go</sl-button>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-button",
+		vecty.Markup(
+			vecty.Property("variant", "primary"),
+			vecty.Property("pill", true),
+			vecty.Attribute("href", "/home"),
+			&vecty.EventListener{Name: "sl-click", Listener: c.onClick},
+		),
+		vecty.Text("Go"),
+	)
+}`)
+}
+
+func TestCompiler_CustomElementPropertiesWithAnEmptyValueAreEmptyStrings(t *testing.T) {
+	out, err := compileRender(t, shoelaceCompiler(t), `<sl-button variant="" pill />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-button",
+		vecty.Markup(
+			vecty.Property("variant", ""),
+			vecty.Property("pill", true),
+		),
+	)
+}`)
+}
+
+func TestCompiler_CustomElementPropertiesAreConvertedToTheirType(t *testing.T) {
+	c := NewCompiler()
+	require.NoError(t, c.RegisterElement(CustomElement{
+		Tag:           "sl-input",
+		Properties:    []string{"maxlength", "step", "min", "clearable", "label"},
+		PropertyTypes: map[string]string{"maxLength": "number", "step": "number", "min": "number", "clearable": "bool", "label": "string"},
+	}))
+	out, err := compileRender(t, c, `<sl-input maxlength="10" step="0.5" min="-1" clearable="false" label="10" />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-input",
+		vecty.Markup(
+			vecty.Property("maxlength", 10),
+			vecty.Property("step", 0.5),
+			vecty.Property("min", -1),
+			vecty.Property("clearable", false),
+			vecty.Property("label", "10"),
+		),
+	)
+}`)
+
+	_, err = compileRender(t, c, `<sl-input maxlength="ten" />`)
+	require.EqualError(t, err, "comp.vtpl:4: property 'maxlength' must be a number, but was 'ten'")
+	_, err = compileRender(t, c, `<sl-input clearable="yes" />`)
+	require.EqualError(t, err, "comp.vtpl:4: property 'clearable' must be true or false, but was 'yes'")
+}
+
+func TestCompiler_RegisterElementValidatesPropertyTypes(t *testing.T) {
+	err := NewCompiler().RegisterElement(CustomElement{Tag: "sl-input", Properties: []string{"size"}, PropertyTypes: map[string]string{"size": "int"}})
+	require.EqualError(t, err, "invalid type 'int' for property 'size' of custom element 'sl-input', must be string, number or bool")
+	err = NewCompiler().RegisterElement(CustomElement{Tag: "sl-input", PropertyTypes: map[string]string{"size": "number"}})
+	require.EqualError(t, err, "the type of 'size' is given for custom element 'sl-input', but it is not one of its properties")
+}
+
+func TestCompiler_CustomEventModifiers(t *testing.T) {
+	out, err := compileRender(t, shoelaceCompiler(t), `<sl-button on:sl-blur.stop={c.onBlur} />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-button",
+		vecty.Markup((&vecty.EventListener{Name: "sl-blur", Listener: c.onBlur}).StopPropagation()),
+	)
+}`)
+}
+
+func TestCompiler_CustomElementGlobalAttributesAreAllowed(t *testing.T) {
+	_, err := compileRender(t, shoelaceCompiler(t),
+		`<sl-button id="save" class="big" slot="footer" data-id="1" aria-label="Save" onclick={c.onClick} />`)
+	require.NoError(t, err)
+}
+
+func TestCompiler_CustomElementUnknownAttributeIsAnError(t *testing.T) {
+	_, err := compileRender(t, shoelaceCompiler(t), `<sl-button varient="primary" />`)
+	require.EqualError(t, err, "comp.vtpl:4: unknown attribute 'varient' for custom element <sl-button>")
+}
+
+func TestCompiler_CustomElementUnknownEventIsAnError(t *testing.T) {
+	_, err := compileRender(t, shoelaceCompiler(t), `<sl-button on:sl-focus={c.onFocus} />`)
+	require.EqualError(t, err, "comp.vtpl:4: unknown event 'sl-focus' in attribute 'on:sl-focus'")
+}
+
+func TestCompiler_UnregisteredCustomElementsAreNotChecked(t *testing.T) {
+	_, err := compileRender(t, shoelaceCompiler(t), `<my-widget anything="1" />`)
+	require.NoError(t, err)
+}
+
+func TestCompiler_RegisterElementRequiresHyphen(t *testing.T) {
+	err := NewCompiler().RegisterElement(CustomElement{Tag: "button"})
+	require.EqualError(t, err, "invalid custom element 'button', custom element names must contain a '-'")
+}
+
+func TestCompiler_LoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tvecty.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "elements": [
+    {"tag": "sl-input", "properties": ["value"], "events": ["sl-input"]}
+  ]
+}`), 0644))
+	c := NewCompiler()
+	require.NoError(t, c.LoadConfig(path))
+	out, err := compileRender(t, c, `<sl-input value={c.name} on:sl-input={c.onInput} />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-input",
+		vecty.Markup(
+			vecty.Property("value", c.name),
+			&vecty.EventListener{Name: "sl-input", Listener: c.onInput},
+		),
+	)
+}`)
+}
+
+func TestCompiler_DisallowUnsafeHTML(t *testing.T) {
+	c := NewCompiler()
+	c.DisallowUnsafeHTML = true
+	_, err := compileRender(t, c, `<div>{unsafe:c.html}</div>`)
+	require.EqualError(t, err, "comp.vtpl:4: unsafe html is not allowed, but was used in '{unsafe:c.html}'")
+}
+
+func moneyCompiler(t *testing.T) *Compiler {
+	c := NewCompiler()
+	require.NoError(t, c.RegisterModifier(Modifier{Name: "money", Func: "format.Money", Import: "example.com/app/format"}))
+	require.NoError(t, c.RegisterModifier(Modifier{Name: "truncate", Func: "format.Truncate", Import: "example.com/app/format"}))
+	return c
+}
+
+func TestCompiler_UserDefinedModifiersAndPipelines(t *testing.T) {
+	out, err := compileRender(t, moneyCompiler(t), `<p title="{c.name |> truncate(10)}">
+		<em>{money:c.price}</em>
+		<i>{c.total |> money |> s}</i>
+		{c.count |> d}
+		{c.when |> t("2006-01-02")}
+		{c.n |> f("%d items")}
+		{c.flags | c.mask}
+	</p>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import (
+	"example.com/app/format"
+	"fmt"
+	"strconv"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Paragraph(
+		vecty.Markup(
+			vecty.Attribute("title", format.Truncate(c.name, 10)),
+		),
+		elem.Emphasis(
+			vecty.Text(format.Money(c.price)),
+		),
+		elem.Italic(
+			vecty.Text(format.Money(c.total)),
+		),
+		vecty.Text(strconv.FormatInt(int64(c.count), 10)),
+		vecty.Text(c.when.Format("2006-01-02")),
+		vecty.Text(fmt.Sprintf("%d items", c.n)),
+		c.flags|c.mask)
+}`)
+}
+
+func TestCompiler_OrNamedLikeAModifierIsNotAPipeline(t *testing.T) {
+	out, err := compileRender(t, moneyCompiler(t), `<p>{c.a | d}{c.flags | money || c.b}</p>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Paragraph(c.a|d, c.flags|money || c.b)
+}`)
+}
+
+func TestCompiler_UnknownModifiersArePositionedErrors(t *testing.T) {
+	_, err := compileRender(t, moneyCompiler(t), `<p>
+		<b>{c.price |> mony |> s}</b>
+	</p>`)
+	require.EqualError(t, err, "comp.vtpl:5: unknown modifier 'mony' in expression: 'c.price |> mony |> s'")
+
+	_, err = compileRender(t, moneyCompiler(t), `<p>
+
+		{mony:c.price}
+	</p>`)
+	require.EqualError(t, err, "comp.vtpl:6: unknown modifier 'mony' in expression: 'mony:c.price'")
+}
+
+func TestCompiler_SModifierMustEndAPipeline(t *testing.T) {
+	_, err := compileRender(t, moneyCompiler(t), `<p>{c.price |> s |> money}</p>`)
+	require.EqualError(t, err, "comp.vtpl:4: the s modifier must be the last stage of a pipeline, but was used in 'c.price |> s |> money'")
+}
+
+func TestCompiler_RegisterModifierValidatesTheName(t *testing.T) {
+	err := NewCompiler().RegisterModifier(Modifier{Name: "s", Func: "strings.ToUpper"})
+	require.EqualError(t, err, "invalid modifier name 's', the name is used by a built in modifier")
+
+	err = NewCompiler().RegisterModifier(Modifier{Name: "to-upper", Func: "strings.ToUpper"})
+	require.EqualError(t, err, "invalid modifier name 'to-upper', modifier names must be lower case letters")
+}
+
+func TestCompiler_LoadConfigModifiers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tvecty.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "modifiers": [
+    {"name": "upper", "func": "strings.ToUpper", "import": "strings"}
+  ]
+}`), 0644))
+	c := NewCompiler()
+	require.NoError(t, c.LoadConfig(path))
+	out, err := compileRender(t, c, `<p>{upper:c.name}</p>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import "strings"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Paragraph(
+		vecty.Text(strings.ToUpper(c.name)),
+	)
+}`)
+}
+
+func TestCompiler_ScopesStyles(t *testing.T) {
+	in := `package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div class="card">
+		<style scoped>
+			.card, .card > p { padding: 1px; }
+			a:hover, p::before { content: "}"; }
+			@media (max-width: 10px) {
+				.card { padding: 0; }
+			}
+			@keyframes spin { from { opacity: 0; } }
+		</style>
+		<p>Hi</p>
+	</div>
+}
+`
+	out := bytes.NewBuffer(nil)
+	css := bytes.NewBuffer(nil)
+	require.NoError(t, NewCompiler().ConvertToVectyWithStyles("comp.vtpl", out, css, []byte(in)))
+	requireEqStr(t, out.String(), `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("tv-c74ca415"),
+			vecty.Class("card"),
+		),
+		elem.Paragraph(
+			vecty.Markup(
+				vecty.Class("tv-c74ca415"),
+			),
+			vecty.Text("Hi"),
+		),
+	)
+}`)
+	requireEqStr(t, css.String(), `
+.card.tv-c74ca415, .card > p.tv-c74ca415 { padding: 1px; }
+			a:hover.tv-c74ca415, p.tv-c74ca415::before { content: "}"; }
+			@media (max-width: 10px) {
+				.card.tv-c74ca415 { padding: 0; }
+			}
+			@keyframes spin { from { opacity: 0; } }`)
+}
+
+func TestCompiler_ScopedStylesRequireACssOutput(t *testing.T) {
+	_, err := compileRender(t, NewCompiler(), `<div><style scoped>p { margin: 0; }</style></div>`)
+	require.EqualError(t, err, "'comp.vtpl' contains scoped styles, but there is no css output")
+}
+
+func TestCompiler_StripComments(t *testing.T) {
+	c := NewCompiler()
+	c.StripComments = true
+	out, err := compileRender(t, c, `<div>
+		<!-- removed -->
+		<p>Hi</p>
+	</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		elem.Paragraph(
+			vecty.Text("Hi"),
+		),
+	)
+}`)
+}
@@ -0,0 +1,188 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"strings"
+)
+
+// CustomElement describes a custom element (web component) so its properties, attributes and events can be compiled
+// correctly. Attributes of a registered element that are not described, and are not global html attributes, are
+// reported as errors.
+type CustomElement struct {
+	// Tag is the name of the element, ex. sl-button.
+	Tag string `json:"tag"`
+	// Properties are set with vecty.Property, ex. variant. A property without a value is set to true.
+	Properties []string `json:"properties"`
+	// PropertyTypes are the types of the properties whose values are not strings, by property name, ex.
+	// {"maxlength": "number"}. Static values of these properties are converted to the type, ex. maxlength="10" is set
+	// to 10. The types are string, number and bool.
+	PropertyTypes map[string]string `json:"propertyTypes"`
+	// Attributes are set with vecty.Attribute.
+	Attributes []string `json:"attributes"`
+	// Events are the custom events the element dispatches, ex. sl-click, and are bound with on:sl-click.
+	Events []string `json:"events"`
+}
+
+// Attributes that apply to every html element.
+var globalAttributes = map[string]bool{
+	"accesskey":       true,
+	"autocapitalize":  true,
+	"autofocus":       true,
+	"class":           true,
+	"contenteditable": true,
+	"dir":             true,
+	"draggable":       true,
+	"enterkeyhint":    true,
+	"exportparts":     true,
+	"hidden":          true,
+	"id":              true,
+	"inert":           true,
+	"inputmode":       true,
+	"is":              true,
+	"lang":            true,
+	"nonce":           true,
+	"part":            true,
+	"role":            true,
+	"slot":            true,
+	"spellcheck":      true,
+	"style":           true,
+	"tabindex":        true,
+	"title":           true,
+	"translate":       true,
+}
+
+// RegisterElement describes a custom element to the compiler.
+func (c *Compiler) RegisterElement(el CustomElement) error {
+	if !strings.Contains(el.Tag, "-") {
+		return fmt.Errorf("invalid custom element '%s', custom element names must contain a '-'", el.Tag)
+	}
+	for name, typ := range el.PropertyTypes {
+		if _, ok := el.findProperty(name); !ok {
+			return fmt.Errorf("the type of '%s' is given for custom element '%s', but it is not one of its properties", name, el.Tag)
+		}
+		if typ != "string" && typ != "number" && typ != "bool" {
+			return fmt.Errorf("invalid type '%s' for property '%s' of custom element '%s', must be string, number or bool", typ, name, el.Tag)
+		}
+	}
+	c.elements[strings.ToLower(el.Tag)] = &el
+	return nil
+}
+
+func (el *CustomElement) findProperty(attrName string) (string, bool) {
+	return findFold(el.Properties, attrName)
+}
+
+// Gets the type of a property, which is a string unless another type is given in PropertyTypes.
+func (el *CustomElement) propertyType(propName string) string {
+	for name, typ := range el.PropertyTypes {
+		if strings.EqualFold(name, propName) {
+			return typ
+		}
+	}
+	return "string"
+}
+
+func (el *CustomElement) findAttribute(attrName string) (string, bool) {
+	return findFold(el.Attributes, attrName)
+}
+
+func (el *CustomElement) findEvent(eventName string) (string, bool) {
+	return findFold(el.Events, eventName)
+}
+
+// Finds the value matching s ignoring case, since attribute names are lower cased when parsed.
+func findFold(values []string, s string) (string, bool) {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Parse an attribute of a registered custom element. Returns nil if the attribute is not specific to the element, in
+// which case it is handled like an attribute of any other element.
+func parseCustomElementAttribute(ctx *tagContext, el *CustomElement, attr *html.Attr) (dst.Expr, error) {
+	if propName, isProp := el.findProperty(attr.Name); isProp {
+		// Only an attribute without a value sets the property to true, ex. <sl-button pill>, variant="" is an empty
+		// string.
+		if !attr.HasValue {
+			return simpleCallExpr("vecty", "Property", []dst.Expr{stringLit(propName), dst.NewIdent("true")}), nil
+		}
+		if typ := el.propertyType(propName); typ != "string" && isStaticValue(attr.Value) {
+			value, err := typedPropertyValue(propName, typ, attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			return simpleCallExpr("vecty", "Property", []dst.Expr{stringLit(propName), value}), nil
+		}
+		attrExpr, err := parseSingleAttributeValue(ctx, []dst.Expr{stringLit(propName)}, attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		attrExpr[1] = ctx.attributeValueExpr(attr, attrExpr[1])
+		return simpleCallExpr("vecty", "Property", attrExpr), nil
+	}
+	if attrName, isAttr := el.findAttribute(attr.Name); isAttr {
+		attrExpr, err := parseSingleAttributeValue(ctx, []dst.Expr{stringLit(attrName)}, attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		attrExpr[1] = ctx.attributeValueExpr(attr, attrExpr[1])
+		return simpleCallExpr("vecty", "Attribute", attrExpr), nil
+	}
+	return nil, nil
+}
+
+// Converts the static value of a property into its type, ex. maxlength="10" with the type number is 10.
+func typedPropertyValue(propName, typ, value string) (dst.Expr, error) {
+	value = strings.TrimSpace(value)
+	switch typ {
+	case "bool":
+		if value != "true" && value != "false" {
+			return nil, fmt.Errorf("property '%s' must be true or false, but was '%s'", propName, value)
+		}
+		return dst.NewIdent(value), nil
+	default:
+		expr, err := parseExpression(value, false)
+		number := expr
+		if unary, ok := expr.(*dst.UnaryExpr); ok && err == nil && unary.Op == token.SUB {
+			number = unary.X
+		}
+		if lit, ok := number.(*dst.BasicLit); err != nil || !ok || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+			return nil, fmt.Errorf("property '%s' must be a number, but was '%s'", propName, value)
+		}
+		return expr, nil
+	}
+}
+
+// Checks an attribute that was not handled by parseCustomElementAttribute is allowed on the element.
+func checkCustomElementAttribute(el *CustomElement, attrName string) error {
+	switch {
+	case globalAttributes[attrName],
+		strings.HasPrefix(attrName, "data-"),
+		strings.HasPrefix(attrName, "aria-"),
+		strings.HasPrefix(attrName, "prop:"),
+		strings.HasPrefix(attrName, "attr:"):
+		return nil
+	}
+	return fmt.Errorf("unknown attribute '%s' for custom element <%s>", attrName, el.Tag)
+}
+
+// Creates the listener for a custom event, ex. &vecty.EventListener{Name: "sl-click", Listener: h}
+func customEventListener(name string, handler dst.Expr) dst.Expr {
+	return &dst.UnaryExpr{
+		Op: token.AND,
+		X: &dst.CompositeLit{
+			Type: &dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("EventListener")},
+			Elts: []dst.Expr{
+				&dst.KeyValueExpr{Key: dst.NewIdent("Name"), Value: stringLit(name)},
+				&dst.KeyValueExpr{Key: dst.NewIdent("Listener"), Value: handler},
+			},
+		},
+		Decs: dst.UnaryExprDecorations{NodeDecs: dst.NodeDecs{Before: dst.NewLine, After: dst.NewLine}},
+	}
+}
@@ -1,15 +1,22 @@
 package tvecty
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/scanner"
+	"go/token"
 	"io"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
+const unsafeModifier = "unsafe"
+
 var (
-	embedModifierRegex = regexp.MustCompile("^([a-z]):(.+)")
+	embedModifierRegex = regexp.MustCompile("^([a-z]+):(.+)")
 )
 
 type embedToken struct {
@@ -18,22 +25,26 @@ type embedToken struct {
 }
 
 // Parse an text within a tag. Unlike attribute parsing body text must be wrapped in vecty.Text()
-func parseTagTextValue(existing []dst.Expr, bodyValue string) ([]dst.Expr, error) {
-	return parseExpressions(existing, bodyValue, true, true)
+func parseTagTextValue(ctx *tagContext, existing []dst.Expr, bodyValue string) ([]dst.Expr, error) {
+	return parseExpressions(ctx, existing, bodyValue, true, true)
 }
 
 // Parse an attribute value into a multiple arguments. Current this is done by split on space, but may change in the
 // future. Example the attribute value "cool stuff" would be created as a two separate arguments
 // (ex vecty.Class("cool", "stuff"))
-func parseMultipleAttributeValue(existing []dst.Expr, attrValue string, addNewLines bool) ([]dst.Expr, error) {
+func parseMultipleAttributeValue(ctx *tagContext, existing []dst.Expr, attrValue string, addNewLines bool) ([]dst.Expr, error) {
 	parts, err := tokenizeExpressionParts(attrValue)
 	if err != nil {
 		return existing, err
 	}
+	return parseMultipleAttributeTokens(ctx, existing, parts, addNewLines)
+}
+
+func parseMultipleAttributeTokens(ctx *tagContext, existing []dst.Expr, parts []embedToken, addNewLines bool) ([]dst.Expr, error) {
 	for _, e := range parts {
 		if !e.isEmbeddedCode {
 			for _, s := range strings.Split(e.value, " ") {
-				expr, err := parseExpressionOrText(s, false, false, addNewLines)
+				expr, err := parseExpressionOrText(ctx, s, false, false, addNewLines)
 				if err != nil {
 					return existing, err
 				}
@@ -43,7 +54,7 @@ func parseMultipleAttributeValue(existing []dst.Expr, attrValue string, addNewLi
 				existing = append(existing, expr)
 			}
 		} else {
-			expr, err := parseExpressionOrText(e.value, true, false, addNewLines)
+			expr, err := parseExpressionOrText(ctx, e.value, true, false, addNewLines)
 			if err != nil {
 				return existing, err
 			}
@@ -60,7 +71,7 @@ func parseMultipleAttributeValue(existing []dst.Expr, attrValue string, addNewLi
 // would be created as a single argument (ex vecty.Attr("first value", "cool stuff"))
 // This also means that the value can only have a single embedded code statement, for example
 // the value "{first} and more text" or "{first}{second}" would be illegal.
-func parseSingleAttributeValue(existing []dst.Expr, attrValue string) ([]dst.Expr, error) {
+func parseSingleAttributeValue(ctx *tagContext, existing []dst.Expr, attrValue string) ([]dst.Expr, error) {
 	parts, err := tokenizeExpressionParts(attrValue)
 	if err != nil {
 		return nil, err
@@ -69,20 +80,51 @@ func parseSingleAttributeValue(existing []dst.Expr, attrValue string) ([]dst.Exp
 		return nil, fmt.Errorf("only single expression is allowed, but was '%s' (must be either a single expression or a strng)", attrValue)
 	}
 	p := parts[0]
-	expr, err := parseExpressionOrText(p.value, p.isEmbeddedCode, false, false)
+	expr, err := parseExpressionOrText(ctx, p.value, p.isEmbeddedCode, false, false)
 	if err != nil {
 		return existing, err
 	}
 	return append(existing, expr), nil
 }
 
-func parseExpressions(existing []dst.Expr, exprs string, wrapText, addNewLines bool) ([]dst.Expr, error) {
+// Checks if an attribute value is known to be a string, ex. "row", {"row"}, {s:c.kind}, {d:c.id} or
+// {c.price |> money}, since modifiers return strings.
+func isStringValue(attrValue string) bool {
+	parts, err := tokenizeExpressionParts(attrValue)
+	if err != nil || len(parts) != 1 {
+		return false
+	}
+	code := strings.TrimSpace(parts[0].value)
+	if !parts[0].isEmbeddedCode {
+		return true
+	}
+	if m := embedModifierRegex.FindStringSubmatch(code); len(m) > 0 {
+		return m[1] != unsafeModifier
+	}
+	if len(splitPipeline(code)) > 1 {
+		return true
+	}
+	expr, err := parseExpression(code, false)
+	lit, ok := expr.(*dst.BasicLit)
+	return err == nil && ok && lit.Kind == token.STRING
+}
+
+func parseExpressions(ctx *tagContext, existing []dst.Expr, exprs string, wrapText, addNewLines bool) ([]dst.Expr, error) {
 	parts, err := tokenizeExpressionParts(exprs)
 	if err != nil {
 		return existing, err
 	}
+	// Embedded code can span lines, so track the line each part starts on for nested templates and errors.
+	line, pos := ctx.line, 0
 	for _, e := range parts {
-		expr, err := parseExpressionOrText(e.value, e.isEmbeddedCode, wrapText, addNewLines)
+		if i := strings.Index(exprs[pos:], e.value); i >= 0 {
+			ctx.line = line + strings.Count(exprs[:pos+i], "\n")
+			pos += i + len(e.value)
+		}
+		if e.isEmbeddedCode && isTemplateComment(e.value) {
+			continue
+		}
+		expr, err := parseExpressionOrText(ctx, e.value, e.isEmbeddedCode, wrapText, addNewLines)
 		if err != nil {
 			return existing, err
 		}
@@ -91,17 +133,18 @@ func parseExpressions(existing []dst.Expr, exprs string, wrapText, addNewLines b
 		}
 		existing = append(existing, expr)
 	}
+	ctx.line = line
 	return existing, nil
 }
 
 // Convert a string into the correct dst.Expr for use in vecty. Variable wrapText determines if a non-embedded code
 // value should be wrapped with vecty.Text(), if not a plain string literal is used instead.
-func parseExpressionOrText(s string, isEmbeddedCode, wrapText, addNewLines bool) (dst.Expr, error) {
+func parseExpressionOrText(ctx *tagContext, s string, isEmbeddedCode, wrapText, addNewLines bool) (dst.Expr, error) {
 	var expr dst.Expr
 	var err error
 	if !isEmbeddedCode {
 		if wrapText {
-			return simpleCallExpr("vecty", "Text", []dst.Expr{stringLit(s)}), nil
+			return simpleCallExpr("vecty", "Text", []dst.Expr{ctx.textExpr(s)}), nil
 		} else {
 			return stringLit(s), err
 		}
@@ -109,21 +152,37 @@ func parseExpressionOrText(s string, isEmbeddedCode, wrapText, addNewLines bool)
 
 	wrapCodeInText := false
 
-	// Parse expression modifiers, ex 's:' in '{s:myExpression}'
+	// Parse expression modifiers, ex 's:' in '{s:myExpression}' or pipelines, ex. '{price |> money}'
+	pipelineExpr, pipelineWrap, isPipeline, err := parsePipeline(ctx, s, wrapText)
+	if err != nil {
+		return nil, err
+	}
 	m := embedModifierRegex.FindStringSubmatch(s)
-	if len(m) > 0 {
-		wrapCodeInText, err = parseEmbedModifier(s, m[1])
+	if isPipeline {
+		s, wrapCodeInText = pipelineExpr, pipelineWrap
+	} else if len(m) > 0 {
+		s, wrapCodeInText, err = parseEmbedModifier(ctx, s, m[1], m[2], wrapText)
 		if err != nil {
 			return nil, err
 		}
-		// Remove the expression modifier from the expression
-		s = m[2]
 	}
 
-	expr, err = parseExpression(s, addNewLines)
+	if hasNestedTemplate(s) {
+		// The templates move the line to their tags, but the embed is positioned where it starts.
+		line := ctx.line
+		expr, err = parseNestedTemplates(ctx, s, addNewLines)
+		if err == nil {
+			ctx.line = line
+		}
+	} else {
+		expr, err = parseExpression(s, addNewLines)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if ctx.typeCheck && wrapText && !isPipeline && len(m) == 0 {
+		ctx.embeds = append(ctx.embeds, &untypedEmbed{expr: expr, src: s, line: ctx.line})
+	}
 	if wrapCodeInText {
 		// Remove extra space so it fits nicely on one line.
 		expr.Decorations().Before = dst.SpaceType(0)
@@ -133,23 +192,84 @@ func parseExpressionOrText(s string, isEmbeddedCode, wrapText, addNewLines bool)
 	return expr, nil
 }
 
-func parseEmbedModifier(fullExpression, m string) (wrapInText bool, err error) {
+// Applies the modifier m to the expression, returning the resulting expression and if it should be wrapped in
+// vecty.Text(). The formatting modifiers only wrap their result in vecty.Text() when wrapText is true, so they can
+// also be used in attribute values, ex. title={f:"%d items", n}.
+func parseEmbedModifier(ctx *tagContext, fullExpression, m, expr string, wrapText bool) (string, bool, error) {
 	switch m {
 	case "s":
 		// wrap the contents in string, ex. {s:"some string"} -> vecty.Text("some string")
-		return true, nil
+		return expr, wrapText, nil
+	case "f":
+		// format the arguments, ex. {f:"%d items", n} -> vecty.Text(fmt.Sprintf("%d items", n))
+		ctx.requireImport("fmt")
+		return "fmt.Sprintf(" + expr + ")", wrapText, nil
+	case "d":
+		// format an integer, ex. {d:n} -> vecty.Text(strconv.FormatInt(int64(n), 10))
+		ctx.requireImport("strconv")
+		return "strconv.FormatInt(int64(" + expr + "), 10)", wrapText, nil
+	case "t":
+		// format a time, ex. {t:when, "2006-01-02"} -> vecty.Text(when.Format("2006-01-02"))
+		args := splitTopLevel(expr, ',')
+		if len(args) != 2 {
+			return "", false, fmt.Errorf("the t modifier expects a time and a layout, ex. {t:when, \"2006-01-02\"}, but was '%s'", fullExpression)
+		}
+		timeStr := strings.TrimSpace(args[0])
+		timeExpr, err := parseExpression(timeStr, false)
+		if err != nil {
+			return "", false, err
+		}
+		switch timeExpr.(type) {
+		case *dst.BinaryExpr, *dst.UnaryExpr, *dst.StarExpr:
+			timeStr = "(" + timeStr + ")"
+		}
+		return timeStr + ".Format(" + strings.TrimSpace(args[1]) + ")", wrapText, nil
+	case unsafeModifier:
+		// vecty.UnsafeHTML replaces the contents of the element so it's handled by the element, see unsafeHTMLContent.
+		return "", false, fmt.Errorf("the unsafe modifier must be the only content of an element, but was used in '%s'", fullExpression)
 	default:
-		return false, fmt.Errorf("invalid expression modifier '%s' in expression: '%s'", m, fullExpression)
+		custom, ok := ctx.modifiers[m]
+		if !ok {
+			return "", false, fmt.Errorf("unknown modifier '%s' in expression: '%s'", m, fullExpression)
+		}
+		if custom.Import != "" {
+			ctx.requireImport(custom.Import)
+		}
+		return custom.Func + "(" + expr + ")", wrapText, nil
 	}
 }
 
+// Checks if embedded code is only a comment, ex. {/* a comment */}, these are template comments and never emitted.
+func isTemplateComment(code string) bool {
+	code = strings.TrimSpace(code)
+	return strings.HasPrefix(code, "/*") && strings.HasSuffix(code, "*/") && strings.Count(code, "*/") == 1
+}
+
 // Tokenizes a string containing embedded code into a set of tokens that are either code or text.
 // Ex. "{first} and some text {second}", in the case the values 'first' and 'second' would be parsed as code, while the
 // value "and some text" would be parsed as text.
+//
+// Embedded code can contain braces as long as they are balanced, ex. {vecty.ClassMap{"active": on}}, braces within
+// Go strings are ignored. Code can also contain nested templates, ex. {cond && <b>{name}</b>}, and new lines within
+// brackets, ex. {items.Map(func(i Item) vecty.ComponentOrHTML {\n return <li/>\n})}.
 func tokenizeExpressionParts(exprs string) (out []embedToken, err error) {
+	return tokenizeEmbeds(exprs, true)
+}
+
+// Tokenizes a string containing embedded code, if trimText is false the text tokens are left as is instead of being
+// trimmed and split on new lines. This is useful when the spacing is significant, ex. "0 {px}" in a style value.
+func tokenizeEmbeds(exprs string, trimText bool) (out []embedToken, err error) {
+	trim := strings.TrimSpace
+	if !trimText {
+		trim = func(s string) string { return s }
+	}
 	r := strings.NewReader(exprs)
 	currentToken := embedToken{}
 	currentValue := strings.Builder{}
+	depth := 0
+	// Depth of the brackets, braces and parenthesis within the embedded code.
+	nesting := 0
+	var quoteChar rune
 	defer func() {
 		if err == nil && out == nil {
 			// If exprs if empty, they needs to exist at least one value, in this case an empty string literal.
@@ -162,32 +282,116 @@ func tokenizeExpressionParts(exprs string) (out []embedToken, err error) {
 			if currentToken.isEmbeddedCode {
 				return nil, fmt.Errorf("missing closing '}' tag for embedded code in '%s'", exprs)
 			}
-			out = tryAppendAttributeToken(out, currentToken, strings.TrimSpace(currentValue.String()))
+			out = tryAppendAttributeToken(out, currentToken, trim(currentValue.String()))
 			return out, nil
 		}
 		if err != nil {
 			return nil, err
 		}
+		// Read Go strings within embedded code as is, so braces within them are not counted.
+		if quoteChar != 0 {
+			currentValue.WriteRune(c)
+			switch c {
+			case '\\':
+				if quoteChar != '`' {
+					escaped, _, err := r.ReadRune()
+					if err != nil {
+						return nil, fmt.Errorf("missing closing '}' tag for embedded code in '%s'", exprs)
+					}
+					currentValue.WriteRune(escaped)
+				}
+			case quoteChar:
+				quoteChar = 0
+			}
+			continue
+		}
 		switch c {
 		case '{':
 			if currentToken.isEmbeddedCode {
-				return nil, fmt.Errorf("cannot have nested expressions in expressions '%s'", exprs)
+				depth++
+				nesting++
+				currentValue.WriteRune(c)
+				continue
 			}
-			out = tryAppendAttributeToken(out, currentToken, strings.TrimSpace(currentValue.String()))
+			out = tryAppendAttributeToken(out, currentToken, trim(currentValue.String()))
 			currentToken = embedToken{isEmbeddedCode: true}
 			currentValue = strings.Builder{}
 		case '}':
 			if !currentToken.isEmbeddedCode {
 				return nil, fmt.Errorf("unexpected '}' in expressions '%s'", exprs)
 			}
+			if depth > 0 {
+				depth--
+				nesting--
+				currentValue.WriteRune(c)
+				continue
+			}
 			out = tryAppendAttributeToken(out, currentToken, currentValue.String())
 			currentToken = embedToken{}
 			currentValue = strings.Builder{}
+			nesting = 0
+		case '(', '[':
+			if currentToken.isEmbeddedCode {
+				nesting++
+			}
+			currentValue.WriteRune(c)
+		case ')', ']':
+			if currentToken.isEmbeddedCode {
+				nesting--
+			}
+			currentValue.WriteRune(c)
+		case '<':
+			currentValue.WriteRune(c)
+			start := len(exprs) - r.Len() - 1
+			if !currentToken.isEmbeddedCode || !isTemplateStart(exprs[:start], exprs[start+1:]) {
+				continue
+			}
+			// Read nested templates as is, ex. <b>it's {name}</b> in {cond && <b>it's {name}</b>}, since their text
+			// is not code.
+			tag, htmlSrc, err := html.ParseHtml(bytes.NewReader([]byte(exprs[start:])))
+			if err != nil {
+				return nil, err
+			}
+			if tag == nil {
+				continue
+			}
+			currentValue.Write(htmlSrc[1:])
+			if _, err := r.Seek(int64(start+len(htmlSrc)), io.SeekStart); err != nil {
+				return nil, err
+			}
+		case '"', '\'', '`':
+			if currentToken.isEmbeddedCode {
+				quoteChar = c
+			}
+			currentValue.WriteRune(c)
+		case '/':
+			currentValue.WriteRune(c)
+			if !currentToken.isEmbeddedCode || !strings.HasPrefix(exprs[len(exprs)-r.Len():], "*") {
+				continue
+			}
+			// Read comments within embedded code as is, ex. {/* a comment } */}, they may span multiple lines.
+			end := strings.Index(exprs[len(exprs)-r.Len():], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing '*/' for comment in '%s'", exprs)
+			}
+			comment := make([]byte, end+2)
+			if _, err := r.Read(comment); err != nil {
+				return nil, err
+			}
+			currentValue.Write(comment)
 		case '\n':
+			if !trimText && !currentToken.isEmbeddedCode {
+				currentValue.WriteRune(c)
+				continue
+			}
+			if currentToken.isEmbeddedCode && (nesting > 0 || continuesOnNextLine(currentValue.String())) {
+				currentValue.WriteRune(c)
+				continue
+			}
 			if currentToken.isEmbeddedCode {
 				return nil, fmt.Errorf("illegal character '\\n' in embedded code block in expressions '%s'", exprs)
 			}
-			out = tryAppendAttributeToken(out, currentToken, strings.TrimSpace(currentValue.String()))
+			out = tryAppendAttributeToken(out, currentToken, trim(currentValue.String()))
 			currentToken = embedToken{}
 			currentValue = strings.Builder{}
 		default:
@@ -196,6 +400,70 @@ func tokenizeExpressionParts(exprs string) (out []embedToken, err error) {
 	}
 }
 
+// Checks if the code continues on the next line, ex. after the && in {c.ok &&\n<b/>}, which is where Go does not end
+// a statement at a newline.
+func continuesOnNextLine(code string) bool {
+	var s scanner.Scanner
+	src := []byte(code + "\n")
+	s.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	continues := false
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return continues
+		}
+		continues = tok != token.SEMICOLON || lit != "\n"
+	}
+}
+
+// Checks if the < at the end of code starts a nested template, ex. {cond && <b/>}, rather than being a comparison,
+// ex. {a < b}. A template must start with a tag name and be somewhere an expression can start.
+func isTemplateStart(code, rest string) bool {
+	if rest == "" || !unicode.IsLetter(rune(rest[0])) {
+		return false
+	}
+	prev := strings.TrimRightFunc(code, unicode.IsSpace)
+	if prev == "" || strings.ContainsRune("(,{[=:&|!?;", rune(prev[len(prev)-1])) {
+		return true
+	}
+	if !strings.HasSuffix(prev, "return") {
+		return false
+	}
+	prev = strings.TrimSuffix(prev, "return")
+	return prev == "" || !isIdentChar(rune(prev[len(prev)-1]))
+}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Split s on sep, ignoring any separators within braces, brackets, parenthesis or quotes, ex. the ; in
+// url(data:image/png;base64,...) is not split on when splitting a style on ;.
+func splitTopLevel(s string, sep rune) []string {
+	var out []string
+	var quoteChar rune
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch {
+		case quoteChar != 0:
+			if c == quoteChar {
+				quoteChar = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quoteChar = c
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case c == sep && depth == 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
 func tryAppendAttributeToken(toks []embedToken, a embedToken, currentValue string) []embedToken {
 	if currentValue == "" {
 		return toks
@@ -50,7 +50,7 @@ func TestTokenizeExpressionParts_ErrorWhenRandomClosingExpressions(t *testing.T)
 }
 
 func TestParseExpressionOrText_CanParseExpressions(t *testing.T) {
-	expr, err := parseExpressionOrText("first", true, true, false)
+	expr, err := parseExpressionOrText(newTagContext(nil), "first", true, true, false)
 	require.NoError(t, err)
 	requireEqStr(t, tWrapExpr(t, expr), `
 package thing
@@ -61,7 +61,7 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 }
 
 func TestParseExpressionOrText_CanParseExpressionsWithStringModifiers(t *testing.T) {
-	expr, err := parseExpressionOrText("s:wrapped", true, true, false)
+	expr, err := parseExpressionOrText(newTagContext(nil), "s:wrapped", true, true, false)
 	require.NoError(t, err)
 	requireEqStr(t, tWrapExpr(t, expr), `
 package thing
@@ -72,7 +72,7 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 }
 
 func TestParseExpressionOrText_CanParseStrings(t *testing.T) {
-	expr, err := parseExpressionOrText("some string", false, true, false)
+	expr, err := parseExpressionOrText(newTagContext(nil), "some string", false, true, false)
 	require.NoError(t, err)
 	requireEqStr(t, tWrapExpr(t, expr), `
 package thing
@@ -83,7 +83,7 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 }
 
 func TestParseExpressionOrText_CanParseNonWrappedStrings(t *testing.T) {
-	expr, err := parseExpressionOrText("some string", false, false, false)
+	expr, err := parseExpressionOrText(newTagContext(nil), "some string", false, false, false)
 	require.NoError(t, err)
 	requireEqStr(t, tWrapExpr(t, expr), `
 package thing
@@ -92,3 +92,64 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 	"some string"
 }`)
 }
+
+func TestTokenizeExpressionParts_AllowsBalancedBracesInExpressions(t *testing.T) {
+	parts, err := tokenizeExpressionParts(`text {{"a": b, "}": c}} more`)
+	require.NoError(t, err)
+	require.Equal(t, parts, []embedToken{
+		{"text", false},
+		{`{"a": b, "}": c}`, true},
+		{"more", false},
+	})
+}
+
+func TestParseExpressionOrText_CanParseFormattingModifiers(t *testing.T) {
+	ctx := newTagContext(nil)
+	cases := []struct {
+		in       string
+		wrapText bool
+		expected string
+	}{
+		{`f:"%d items", n`, true, `vecty.Text(fmt.Sprintf("%d items", n))`},
+		{`d:c.count`, true, `vecty.Text(strconv.FormatInt(int64(c.count), 10))`},
+		{`t:c.when, "2006-01-02"`, true, `vecty.Text(c.when.Format("2006-01-02"))`},
+		{`t:*c.when, time.Kitchen`, true, `vecty.Text((*c.when).Format(time.Kitchen))`},
+		{`f:"%.2f", price`, false, `fmt.Sprintf("%.2f", price)`},
+	}
+	for _, tc := range cases {
+		expr, err := parseExpressionOrText(ctx, tc.in, true, tc.wrapText, false)
+		require.NoError(t, err)
+		requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	`+tc.expected+`
+}`)
+	}
+	require.Equal(t, map[string]bool{"fmt": true, "strconv": true}, ctx.imports)
+}
+
+func TestParseExpressionOrText_ErrorsOnInvalidModifiers(t *testing.T) {
+	_, err := parseExpressionOrText(newTagContext(nil), `t:c.when`, true, true, false)
+	require.EqualError(t, err, `the t modifier expects a time and a layout, ex. {t:when, "2006-01-02"}, but was 't:c.when'`)
+
+	_, err = parseExpressionOrText(newTagContext(nil), `upper:c.name`, true, true, false)
+	require.EqualError(t, err, "unknown modifier 'upper' in expression: 'upper:c.name'")
+}
+
+func TestTokenizeExpressionParts_ReadsNestedTemplatesAsCode(t *testing.T) {
+	parts, err := tokenizeExpressionParts(`{c.ok && <p>it's {c.name}</p>} {a < b}`)
+	require.NoError(t, err)
+	require.Equal(t, []embedToken{
+		{"c.ok && <p>it's {c.name}</p>", true},
+		{"a < b", true},
+	}, parts)
+}
+
+func TestTokenizeExpressionParts_AllowsNewLinesWithinBrackets(t *testing.T) {
+	parts, err := tokenizeExpressionParts("{items.Map(func(i Item) vecty.ComponentOrHTML {\n\treturn <li/>\n})}")
+	require.NoError(t, err)
+	require.Equal(t, []embedToken{
+		{"items.Map(func(i Item) vecty.ComponentOrHTML {\n\treturn <li/>\n})", true},
+	}, parts)
+}
@@ -0,0 +1,397 @@
+package tvecty
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dave/dst"
+	"strings"
+)
+
+// eventTranslations maps the lower case DOM event name to the function in the vecty event package that binds it.
+// Names are lower case since attribute names are lower cased when the html is parsed.
+var eventTranslations = map[string]string{
+	"abort":                    "Abort",
+	"afterprint":               "AfterPrint",
+	"animationend":             "AnimationEnd",
+	"animationiteration":       "AnimationIteration",
+	"animationstart":           "AnimationStart",
+	"appinstalled":             "ApplicationInstalled",
+	"audioend":                 "AudioEnd",
+	"audiostart":               "AudioStart",
+	"beforeprint":              "BeforePrint",
+	"beforeunload":             "BeforeUnload",
+	"beginevent":               "BeginEvent",
+	"blocked":                  "Blocked",
+	"blur":                     "Blur",
+	"boundary":                 "Boundary",
+	"cached":                   "Cached",
+	"canplay":                  "CanPlay",
+	"canplaythrough":           "CanPlayThrough",
+	"change":                   "Change",
+	"chargingchange":           "ChargingChange",
+	"chargingtimechange":       "ChargingTimeChange",
+	"checking":                 "Checking",
+	"click":                    "Click",
+	"close":                    "Close",
+	"complete":                 "Complete",
+	"compositionend":           "CompositionEnd",
+	"compositionstart":         "CompositionStart",
+	"compositionupdate":        "CompositionUpdate",
+	"contextmenu":              "ContextMenu",
+	"copy":                     "Copy",
+	"cut":                      "Cut",
+	"domcontentloaded":         "DOMContentLoaded",
+	"devicechange":             "DeviceChange",
+	"devicelight":              "DeviceLight",
+	"devicemotion":             "DeviceMotion",
+	"deviceorientation":        "DeviceOrientation",
+	"deviceproximity":          "DeviceProximity",
+	"dischargingtimechange":    "DischargingTimeChange",
+	"dblclick":                 "DoubleClick",
+	"downloading":              "Downloading",
+	"drag":                     "Drag",
+	"dragend":                  "DragEnd",
+	"dragenter":                "DragEnter",
+	"dragleave":                "DragLeave",
+	"dragover":                 "DragOver",
+	"dragstart":                "DragStart",
+	"drop":                     "Drop",
+	"durationchange":           "DurationChange",
+	"emptied":                  "Emptied",
+	"end":                      "End",
+	"endevent":                 "EndEvent",
+	"ended":                    "Ended",
+	"error":                    "Error",
+	"focus":                    "Focus",
+	"focusin":                  "FocusIn",
+	"focusout":                 "FocusOut",
+	"fullscreenchange":         "FullScreenChange",
+	"fullscreenerror":          "FullScreenError",
+	"gamepadconnected":         "GamepadConnected",
+	"gamepaddisconnected":      "GamepadDisconnected",
+	"gotpointercapture":        "GotPointerCapture",
+	"hashchange":               "HashChange",
+	"input":                    "Input",
+	"invalid":                  "Invalid",
+	"keydown":                  "KeyDown",
+	"keypress":                 "KeyPress",
+	"keyup":                    "KeyUp",
+	"languagechange":           "LanguageChange",
+	"levelchange":              "LevelChange",
+	"load":                     "Load",
+	"loadend":                  "LoadEnd",
+	"loadstart":                "LoadStart",
+	"loadeddata":               "LoadedData",
+	"loadedmetadata":           "LoadedMetadata",
+	"lostpointercapture":       "LostPointerCapture",
+	"mark":                     "Mark",
+	"message":                  "Message",
+	"messageerror":             "MessageError",
+	"mousedown":                "MouseDown",
+	"mouseenter":               "MouseEnter",
+	"mouseleave":               "MouseLeave",
+	"mousemove":                "MouseMove",
+	"mouseout":                 "MouseOut",
+	"mouseover":                "MouseOver",
+	"mouseup":                  "MouseUp",
+	"nomatch":                  "NoMatch",
+	"noupdate":                 "NoUpdate",
+	"notificationclick":        "NotificationClick",
+	"obsolete":                 "Obsolete",
+	"offline":                  "Offline",
+	"online":                   "Online",
+	"open":                     "Open",
+	"orientationchange":        "OrientationChange",
+	"pagehide":                 "PageHide",
+	"pageshow":                 "PageShow",
+	"paste":                    "Paste",
+	"pause":                    "Pause",
+	"play":                     "Play",
+	"playing":                  "Playing",
+	"pointercancel":            "PointerCancel",
+	"pointerdown":              "PointerDown",
+	"pointerenter":             "PointerEnter",
+	"pointerleave":             "PointerLeave",
+	"pointerlockchange":        "PointerLockChange",
+	"pointerlockerror":         "PointerLockError",
+	"pointermove":              "PointerMove",
+	"pointerout":               "PointerOut",
+	"pointerover":              "PointerOver",
+	"pointerup":                "PointerUp",
+	"popstate":                 "PopState",
+	"progress":                 "Progress",
+	"push":                     "Push",
+	"pushsubscriptionchange":   "PushSubscriptionChange",
+	"ratechange":               "RateChange",
+	"readystatechange":         "ReadyStateChange",
+	"repeatevent":              "RepeatEvent",
+	"reset":                    "Reset",
+	"resize":                   "Resize",
+	"resourcetimingbufferfull": "ResourceTimingBufferFull",
+	"result":                   "Result",
+	"resume":                   "Resume",
+	"svgabort":                 "SVGAbort",
+	"svgerror":                 "SVGError",
+	"svgload":                  "SVGLoad",
+	"svgresize":                "SVGResize",
+	"svgscroll":                "SVGScroll",
+	"svgunload":                "SVGUnload",
+	"svgzoom":                  "SVGZoom",
+	"scroll":                   "Scroll",
+	"seeked":                   "Seeked",
+	"seeking":                  "Seeking",
+	"select":                   "Select",
+	"selectstart":              "SelectStart",
+	"selectionchange":          "SelectionChange",
+	"show":                     "Show",
+	"slotchange":               "SlotChange",
+	"soundend":                 "SoundEnd",
+	"soundstart":               "SoundStart",
+	"speechend":                "SpeechEnd",
+	"speechstart":              "SpeechStart",
+	"stalled":                  "Stalled",
+	"start":                    "Start",
+	"storage":                  "Storage",
+	"submit":                   "Submit",
+	"success":                  "Success",
+	"suspend":                  "Suspend",
+	"timeupdate":               "TimeUpdate",
+	"timeout":                  "Timeout",
+	"touchcancel":              "TouchCancel",
+	"touchend":                 "TouchEnd",
+	"touchmove":                "TouchMove",
+	"touchstart":               "TouchStart",
+	"transitionend":            "TransitionEnd",
+	"unload":                   "Unload",
+	"updateready":              "UpdateReady",
+	"upgradeneeded":            "UpgradeNeeded",
+	"userproximity":            "UserProximity",
+	"versionchange":            "VersionChange",
+	"visibilitychange":         "VisibilityChange",
+	"voiceschanged":            "VoicesChanged",
+	"volumechange":             "VolumeChange",
+	"waiting":                  "Waiting",
+	"wheel":                    "Wheel",
+}
+
+// Events whose names are also html attributes (ex. <details open>, <ol start="3">). These can only be bound using
+// the on:name or onname spellings, otherwise they are treated as regular attributes.
+var eventAttributeCollisions = map[string]bool{
+	"end":    true,
+	"open":   true,
+	"result": true,
+	"start":  true,
+}
+
+// Key names accepted as event modifiers on keyboard events (ex. keydown.enter) and the KeyboardEvent.key values they
+// match.
+var eventKeyModifiers = map[string]string{
+	"enter":     "Enter",
+	"esc":       "Escape",
+	"escape":    "Escape",
+	"tab":       "Tab",
+	"space":     " ",
+	"up":        "ArrowUp",
+	"down":      "ArrowDown",
+	"left":      "ArrowLeft",
+	"right":     "ArrowRight",
+	"delete":    "Delete",
+	"backspace": "Backspace",
+	"home":      "Home",
+	"end":       "End",
+	"pageup":    "PageUp",
+	"pagedown":  "PageDown",
+}
+
+var keyboardEvents = map[string]bool{
+	"keydown":  true,
+	"keypress": true,
+	"keyup":    true,
+}
+
+// An event attribute, ex. keydown.enter.prevent="" would be the keydown event, filtered to the Enter key, with
+// preventDefault called.
+type eventAttribute struct {
+	attrName string
+	name     string
+	// Function in the vecty event package, empty for custom events.
+	vectyFn         string
+	preventDefault  bool
+	stopPropagation bool
+	keys            []string
+}
+
+// Determines if an attribute binds an event and if so returns the event it binds. Events can be written as
+// click="", on:click="" or onclick="" and can be followed by modifiers, ex. submit.prevent="". Any on:name or onname
+// attribute that is not a known event is an error. Custom events of an element (see CustomElement) can be bound using
+// the on:name and onname spellings, el is nil when the element is not a registered custom element.
+func parseEventAttributeName(attrName string, el *CustomElement) (*eventAttribute, error) {
+	nameParts := strings.Split(attrName, ".")
+	name := nameParts[0]
+	prefixed := true
+	switch {
+	case strings.HasPrefix(name, "on:"):
+		name = name[3:]
+	case strings.HasPrefix(name, "on"):
+		name = name[2:]
+	default:
+		prefixed = false
+	}
+	vectyFn, found := eventTranslations[name]
+	if !prefixed && eventAttributeCollisions[name] {
+		found = false
+	}
+	if !found && prefixed && el != nil {
+		if customName, isCustom := el.findEvent(name); isCustom {
+			name, found = customName, true
+		}
+	}
+	if !found {
+		if prefixed {
+			return nil, fmt.Errorf("unknown event '%s' in attribute '%s'", name, attrName)
+		}
+		return nil, nil
+	}
+	ev := &eventAttribute{attrName: attrName, name: name, vectyFn: vectyFn}
+	for _, modifier := range nameParts[1:] {
+		switch modifier {
+		case "prevent":
+			ev.preventDefault = true
+		case "stop":
+			ev.stopPropagation = true
+		default:
+			key, isKey := eventKeyModifiers[modifier]
+			if !isKey {
+				return nil, fmt.Errorf("unknown event modifier '%s' in attribute '%s'", modifier, attrName)
+			}
+			if !keyboardEvents[name] {
+				return nil, fmt.Errorf("key modifier '%s' can only be used with keyboard events, but was used in attribute '%s'", modifier, attrName)
+			}
+			ev.keys = append(ev.keys, key)
+		}
+	}
+	return ev, nil
+}
+
+// Converts the event attribute with the attribute value as its handler into the vecty event markup, ex.
+// event.Submit(c.onSubmit).PreventDefault()
+func (ev *eventAttribute) toAst(ctx *tagContext, attrValue string) (dst.Expr, error) {
+	handlerStr, err := eventHandlerValue(ev.attrName, attrValue)
+	if err != nil {
+		return nil, err
+	}
+	// Templates nested in the handler, ex. click={func(e *vecty.Event) { c.show(<p>x</p>) }}, are replaced while
+	// checking what kind of handler it is, then converted once the handler is built.
+	checkedStr := handlerStr
+	nested := hasNestedTemplate(handlerStr)
+	if nested {
+		var b bytes.Buffer
+		if _, err := sourceHtmlReplace(newHtmlTracker(), &b, bytes.NewReader([]byte(handlerStr))); err != nil {
+			return nil, err
+		}
+		checkedStr = b.String()
+	}
+	body, needsWrapping, err := eventHandlerBody(ctx, checkedStr)
+	if err != nil {
+		return nil, err
+	}
+	body = strings.Replace(body, checkedStr, handlerStr, 1)
+	if len(ev.keys) > 0 {
+		// The default is only prevented, and propagation only stopped, for the filtered keys, otherwise a
+		// keydown.enter.prevent would prevent typing in an input.
+		var calls []string
+		if ev.preventDefault {
+			calls = append(calls, `e.Call("preventDefault")`)
+		}
+		if ev.stopPropagation {
+			calls = append(calls, `e.Call("stopPropagation")`)
+		}
+		handlerStr = wrapEventHandlerBody(strings.Join(append(calls, body), "\n"), ev.keys)
+	} else if needsWrapping {
+		handlerStr = wrapEventHandlerBody(body, nil)
+	}
+	var handler dst.Expr
+	if nested {
+		line := ctx.line
+		if handler, err = parseNestedTemplates(ctx, handlerStr, false); err == nil {
+			ctx.line = line
+		}
+	} else {
+		handler, err = parseExpression(handlerStr, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out dst.Expr
+	if ev.vectyFn != "" {
+		out = simpleCallExpr("event", ev.vectyFn, []dst.Expr{handler})
+	} else {
+		out = customEventListener(ev.name, handler)
+		if (ev.preventDefault || ev.stopPropagation) && len(ev.keys) == 0 {
+			out = &dst.ParenExpr{X: out}
+		}
+	}
+	if len(ev.keys) > 0 {
+		return out, nil
+	}
+	if ev.preventDefault {
+		out = chainedCallExpr(out, "PreventDefault")
+	}
+	if ev.stopPropagation {
+		out = chainedCallExpr(out, "StopPropagation")
+	}
+	return out, nil
+}
+
+// Get the handler expression from the value of an event attribute. The handler can either be a plain expression
+// (ex. click="c.onClick") or an embedded expression (ex. click={c.onClick}).
+func eventHandlerValue(attrName, attrValue string) (string, error) {
+	parts, err := tokenizeExpressionParts(attrValue)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) > 1 || parts[0].value == "" {
+		return "", fmt.Errorf("event attribute '%s' must be a single handler expression, but was '%s'", attrName, attrValue)
+	}
+	return parts[0].value, nil
+}
+
+// Get the statements that handle the event. Handlers are normally a func(*vecty.Event) expression, but can also be a
+// function declared in the file that takes no arguments (ex. click={c.Save}) or a list of statements
+// (ex. click="{c.count++; vecty.Rerender(c)}"). The latter two are not valid handlers themselves so needsWrapping is
+// true, indicating they need to be wrapped in a func(*vecty.Event).
+func eventHandlerBody(ctx *tagContext, handlerStr string) (body string, needsWrapping bool, err error) {
+	handler, err := parseExpression(handlerStr, false)
+	if err != nil {
+		if stmtErr := checkStatements(handlerStr); stmtErr != nil {
+			return "", false, err
+		}
+		return handlerStr, true, nil
+	}
+	if ctx.isZeroArgFunc(handler) {
+		return handlerStr + "()", true, nil
+	}
+	switch handler.(type) {
+	case *dst.BinaryExpr, *dst.UnaryExpr, *dst.StarExpr:
+		handlerStr = "(" + handlerStr + ")"
+	}
+	return handlerStr + "(e)", false, nil
+}
+
+// Wraps the handler statements in a func(*vecty.Event). If keys are provided the statements are only run when the
+// event is for one of the keys.
+func wrapEventHandlerBody(body string, keys []string) string {
+	if len(keys) == 0 {
+		return fmt.Sprintf("func(e *vecty.Event) {\n%s\n}", body)
+	}
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = fmt.Sprintf("%q", key)
+	}
+	return fmt.Sprintf(`func(e *vecty.Event) {
+	switch e.Get("key").String() {
+	case %s:
+		%s
+	}
+}`, strings.Join(quoted, ", "), body)
+}
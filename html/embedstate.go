@@ -0,0 +1,108 @@
+package html
+
+import "golang.org/x/net/html"
+
+// A level of embedding, either embedded Go code, ex. {cond && <b/>}, or a template nested within embedded code, ex.
+// <b/> in {cond && <b/>}.
+type embedLevel struct {
+	isCode bool
+	// The depth of the braces for code or the depth of the tags for a nested template.
+	depth int
+}
+
+// Tracks if the html being parsed is within embedded Go code, so tags within the code, ex. {cond && <b/>}, are kept
+// as part of the text of the embed rather than being parsed as children of the element.
+type embedState struct {
+	levels []*embedLevel
+	// The quote character of the Go string the code is currently in, if any.
+	quote byte
+}
+
+func (s *embedState) inEmbed() bool {
+	return len(s.levels) > 0
+}
+
+func (s *embedState) top() *embedLevel {
+	if len(s.levels) == 0 {
+		return nil
+	}
+	return s.levels[len(s.levels)-1]
+}
+
+func (s *embedState) push(isCode bool, depth int) {
+	s.levels = append(s.levels, &embedLevel{isCode: isCode, depth: depth})
+}
+
+func (s *embedState) pop() {
+	s.levels = s.levels[:len(s.levels)-1]
+}
+
+// Advances past text, which is either html text or Go code depending on the current level.
+func (s *embedState) scanText(text []byte) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if s.quote != 0 {
+			if c == '\\' && s.quote != '`' {
+				i++
+			} else if c == s.quote {
+				s.quote = 0
+			}
+			continue
+		}
+		top := s.top()
+		if top == nil || !top.isCode {
+			if c == '{' {
+				s.push(true, 0)
+			}
+			continue
+		}
+		switch c {
+		case '"', '`':
+			// Rune literals are not tracked, since an apostrophe is more likely to be text, ex. {n, plural, one {it's one}}.
+			s.quote = c
+		case '{':
+			top.depth++
+		case '}':
+			if top.depth > 0 {
+				top.depth--
+			} else {
+				s.pop()
+			}
+		}
+	}
+}
+
+// Advances past a tag, tags within code start a nested template which ends with the matching end tag. Returns false
+// if the tag cannot be part of the code, ex. the </b> in <b>{n</b>, in which case the code was never closed.
+func (s *embedState) scanTag(tt html.TokenType, raw []byte) bool {
+	if s.quote != 0 {
+		// The tag is part of a Go string, ex. {strings.Contains(s, "<b>")}.
+		s.scanText(raw)
+		return true
+	}
+	top := s.top()
+	if top.isCode {
+		switch tt {
+		case html.StartTagToken:
+			s.push(false, 1)
+		case html.EndTagToken:
+			return false
+		}
+		return true
+	}
+	switch tt {
+	case html.StartTagToken:
+		top.depth++
+	case html.EndTagToken:
+		top.depth--
+		if top.depth == 0 {
+			s.pop()
+		}
+	}
+	return true
+}
+
+func (s *embedState) reset() {
+	s.levels = nil
+	s.quote = 0
+}
@@ -21,12 +21,62 @@ func ParseHtmlString(htmlRaw string) (*TagOrText, error) {
 //
 func ParseHtml(r *bytes.Reader) (tag *TagOrText, htmlSrc []byte, err error) {
 	stack := &tagStack{}
-	remainingAtStart := int64(r.Len())
-	z := html.NewTokenizer(r)
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The number of bytes of src read by the tokens so far.
+	offset := 0
+	z := html.NewTokenizer(bytes.NewReader(src))
 	var lastPop *TagOrText
 	currentDepth := 0
+	line := 1
+	// Text containing embedded code that has not been closed yet, ex. {cond && <b/>}, the tokens up to the end of the
+	// code are added to its text.
+	var embedText *TagOrText
+	embeds := &embedState{}
 	for {
 		tt := z.Next()
+		// The raw source of the token, which may differ from the source the tokenizer is reading when attributes were
+		// quoted.
+		raw := src[offset : offset+len(z.Raw())]
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			if end, quoted := quoteBraceAttributes(src[offset:]); quoted != nil {
+				// Tokenize the rest of the source with the attributes of the tag quoted.
+				z = html.NewTokenizer(io.MultiReader(bytes.NewReader(quoted), bytes.NewReader(src[offset+end:])))
+				tt = z.Next()
+				raw = src[offset : offset+end]
+			}
+		}
+		offset += len(raw)
+		// The line the token starts on, the line is advanced past the token at the start of the next loop.
+		tokenLine := line
+		line += bytes.Count(raw, []byte("\n"))
+
+		if embedText != nil {
+			inCode := tt != html.ErrorToken
+			if tt == html.TextToken || tt == html.CommentToken {
+				embeds.scanText(raw)
+			} else if inCode {
+				inCode = embeds.scanTag(tt, raw)
+			}
+			if inCode {
+				embedText.Text += string(raw)
+			} else {
+				// The code was never closed, the error is reported when the text is converted.
+				embeds.reset()
+			}
+			if !embeds.inEmbed() {
+				embedText.Text = strings.TrimSpace(embedText.Text)
+				if err := stack.pushChild(embedText); err != nil {
+					return lastPop, nil, err
+				}
+				embedText = nil
+			}
+			if inCode {
+				continue
+			}
+		}
 
 		switch tt {
 		case html.ErrorToken:
@@ -61,30 +111,53 @@ func ParseHtml(r *bytes.Reader) (tag *TagOrText, htmlSrc []byte, err error) {
 				r.Reset(txtb)
 				return nil, nil, nil
 			}
-			tag := &TagOrText{Text: txtT}
+			leadingSpace := txt[:strings.Index(txt, txtT)]
+			tag := &TagOrText{Text: txtT, Line: tokenLine + strings.Count(leadingSpace, "\n")}
+			embeds.scanText(txtb)
+			if embeds.inEmbed() {
+				// Keep the trailing space, it may separate the code from a nested template, ex. {return <b/>}.
+				tag.Text = txt[len(leadingSpace):]
+				embedText = tag
+				continue
+			}
 			if err := stack.pushChild(tag); err != nil {
 				return lastPop, nil, err
 			}
+		case html.CommentToken:
+			// Comments outside of the html are not part of it.
+			if stack.isEmpty() {
+				continue
+			}
+			txt := string(z.Text())
+			txtT := strings.TrimSpace(txt)
+			leadingSpace := txt[:strings.Index(txt, txtT)]
+			comment := &TagOrText{Text: txtT, IsComment: true, Line: tokenLine + strings.Count(leadingSpace, "\n")}
+			if err := stack.pushChild(comment); err != nil {
+				return lastPop, nil, err
+			}
 		case html.SelfClosingTagToken:
+			// Copy the tokenized tag before the tokenizer lower cases it.
+			tokenized := append([]byte(nil), z.Raw()...)
 			tnb, hasAttr := z.TagName()
 			//fmt.Println(strings.Repeat("-", currentDepth), string(tnb), "(self-closing)", string(z.Raw()))
-			tag := &TagOrText{TagName: string(tnb)}
+			tag := &TagOrText{TagName: string(tnb), RawTagName: rawTagName(raw, tnb), Line: tokenLine}
 			if hasAttr {
-				tag.Attr = parseAttributes(z)
+				tag.Attr = parseAttributes(z, tokenized)
 			}
 			if currentDepth == 0 {
-				return finalizeTagParsing(r, z, remainingAtStart, tag)
+				return finalizeTagParsing(r, src, offset, tag)
 			}
 			if err := stack.pushChild(tag); err != nil {
 				return lastPop, nil, err
 			}
 		case html.StartTagToken:
 			currentDepth += 1
+			tokenized := append([]byte(nil), z.Raw()...)
 			tnb, hasAttr := z.TagName()
 			//fmt.Println(strings.Repeat("-", currentDepth - 1), string(tnb), "starting", string(z.Raw()))
-			tag := &TagOrText{TagName: string(tnb)}
+			tag := &TagOrText{TagName: string(tnb), RawTagName: rawTagName(raw, tnb), Line: tokenLine}
 			if hasAttr {
-				tag.Attr = parseAttributes(z)
+				tag.Attr = parseAttributes(z, tokenized)
 			}
 			stack.push(tag)
 		case html.EndTagToken:
@@ -101,47 +174,237 @@ func ParseHtml(r *bytes.Reader) (tag *TagOrText, htmlSrc []byte, err error) {
 			}
 			//fmt.Println(strings.Repeat("-", currentDepth), "/" + string(tnb), "closing")
 			if currentDepth == 0 {
-				return finalizeTagParsing(r, z, remainingAtStart, lastPop)
+				return finalizeTagParsing(r, src, offset, lastPop)
 			}
 		}
 	}
 }
 
-func finalizeTagParsing(r *bytes.Reader, z *html.Tokenizer, remainingAtStart int64, lastPop *TagOrText) (*TagOrText, []byte, error) {
-	// Extract the html content just parsed into html and return it to the caller.
+// Returns the html just parsed, the first parsed bytes of src, and resets r to the remaining bytes after the html.
+func finalizeTagParsing(r *bytes.Reader, src []byte, parsed int, lastPop *TagOrText) (*TagOrText, []byte, error) {
+	r.Reset(src[parsed:])
+	return lastPop, src[:parsed], nil
+}
 
-	// [       orig buffer size                        ]
-	//               [ remaining at start              ]
-	//                            [ remaining buffer   ]
-	// [ start index ]
-	//               [ start html ]
-	remainingBuffer := z.Buffered()
-	remainingPastBuffer, err := io.ReadAll(r)
-	if err != nil {
-		return nil, nil, err
+// Gets the tag name as written in the source, since the tokenizer lower cases tag names, ex. linearGradient rather
+// than lineargradient.
+func rawTagName(raw, name []byte) string {
+	if len(raw) > len(name) && bytes.EqualFold(raw[1:len(name)+1], name) {
+		return string(raw[1 : len(name)+1])
 	}
-	remainingAll := make([]byte, len(remainingBuffer)+len(remainingPastBuffer))
-	copy(remainingAll, remainingBuffer)
-	copy(remainingAll[len(remainingBuffer):], remainingPastBuffer)
-
-	origBufferSize := r.Size()
-	startIndex := origBufferSize - remainingAtStart
-	startHtmlLen := origBufferSize - int64(len(remainingAll)) - startIndex
-	startHtml := make([]byte, startHtmlLen)
-	_, err = r.ReadAt(startHtml, startIndex)
-	// Then set the r to whatever bytes were remaining after parsing the html.
-	r.Reset(remainingAll)
-	return lastPop, startHtml, err
+	return string(name)
 }
 
-func parseAttributes(z *html.Tokenizer) []*Attr {
+func parseAttributes(z *html.Tokenizer, raw []byte) []*Attr {
+	rawAttrs := rawAttributes(raw)
 	var out []*Attr
 	for {
 		key, val, more := z.TagAttr()
-		out = append(out, &Attr{string(key), string(val)})
+		out = append(out, &Attr{Name: string(key), RawName: string(key), Value: string(val), HasValue: len(val) > 0})
 		if !more {
 			break
 		}
 	}
+	// The tokenizer lower cases attribute names and does not report if there was a value, so use the attributes as
+	// written when they can be matched up.
+	if len(rawAttrs) == len(out) {
+		for i, attr := range out {
+			if strings.ToLower(rawAttrs[i].name) == attr.Name {
+				attr.RawName = rawAttrs[i].name
+				attr.HasValue = rawAttrs[i].hasValue
+			}
+		}
+	}
+	for _, attr := range out {
+		if attr.Name == spreadAttrName {
+			attr.RawName = "{..." + attr.Value + "}"
+			attr.Name = strings.ToLower(attr.RawName)
+			attr.Value = ""
+			attr.HasValue = false
+		}
+	}
 	return out
 }
+
+// An attribute as written in the source of a tag.
+type rawAttribute struct {
+	name     string
+	hasValue bool
+}
+
+// Reads the attributes, as written, from the raw source of a tag, ex. <svg viewBox="0 0 10 10"> would have the
+// attribute viewBox with a value. This follows the same rules as the html tokenizer for where names and values start
+// and end.
+func rawAttributes(raw []byte) []rawAttribute {
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f'
+	}
+	i := 1
+	for i < len(raw) && !isSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' {
+		i++
+	}
+	var attrs []rawAttribute
+	for i < len(raw) {
+		for i < len(raw) && (isSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' {
+			break
+		}
+		start := i
+		for i < len(raw) {
+			c := raw[i]
+			if isSpace(c) || c == '/' || c == '>' || (c == '=' && i != start) {
+				break
+			}
+			i++
+		}
+		attrs = append(attrs, rawAttribute{name: string(raw[start:i])})
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		if i >= len(raw) || raw[i] != '=' {
+			continue
+		}
+		attrs[len(attrs)-1].hasValue = true
+		i++
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		if i < len(raw) && (raw[i] == '"' || raw[i] == '\'') {
+			quote := raw[i]
+			i++
+			for i < len(raw) && raw[i] != quote {
+				i++
+			}
+			i++
+			continue
+		}
+		for i < len(raw) && !isSpace(raw[i]) && raw[i] != '>' {
+			i++
+		}
+	}
+	return attrs
+}
+
+// The name markup spreads are given while tokenizing, see quoteBraceAttributes.
+const spreadAttrName = "{...}"
+
+// Quotes the attribute values of the tag at the start of src which are embedded code, ex. click={c.n++; c.save()},
+// since the html tokenizer ends unquoted values at a space or '>'. The values are read up to the matching brace and
+// escaped so the tokenizer reads them as written, markup spreads are quoted the same way. Returns the length of the
+// tag in src and the tag with the values quoted, or nil if the tag has no such values.
+func quoteBraceAttributes(src []byte) (int, []byte) {
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f'
+	}
+	var out bytes.Buffer
+	// The end of the source already written to out.
+	written := 0
+	i := 1
+	for i < len(src) && !isSpace(src[i]) && src[i] != '/' && src[i] != '>' {
+		i++
+	}
+	for i < len(src) {
+		for i < len(src) && (isSpace(src[i]) || src[i] == '/') {
+			i++
+		}
+		if i >= len(src) || src[i] == '>' {
+			break
+		}
+		if bytes.HasPrefix(src[i:], []byte("{...")) {
+			// A markup spread, ex. {...c.Markup(a, b)}, the expression becomes the value of a {...} attribute.
+			end := matchingBrace(src, i)
+			if end < 0 {
+				return 0, nil
+			}
+			out.Write(src[written:i])
+			out.WriteString(spreadAttrName + `="`)
+			out.WriteString(attrValueEscaper.Replace(string(src[i+len("{...") : end-1])))
+			out.WriteByte('"')
+			i, written = end, end
+			continue
+		}
+		start := i
+		for i < len(src) {
+			c := src[i]
+			if isSpace(c) || c == '/' || c == '>' || (c == '=' && i != start) {
+				break
+			}
+			i++
+		}
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i >= len(src) || src[i] != '=' {
+			continue
+		}
+		i++
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+			quote := src[i]
+			i++
+			for i < len(src) && src[i] != quote {
+				i++
+			}
+			i++
+			continue
+		}
+		if i < len(src) && src[i] == '{' {
+			end := matchingBrace(src, i)
+			if end < 0 {
+				return 0, nil
+			}
+			out.Write(src[written:i])
+			out.WriteByte('"')
+			out.WriteString(attrValueEscaper.Replace(string(src[i:end])))
+			out.WriteByte('"')
+			i, written = end, end
+			continue
+		}
+		for i < len(src) && !isSpace(src[i]) && src[i] != '>' {
+			i++
+		}
+	}
+	if written == 0 || i >= len(src) {
+		return 0, nil
+	}
+	out.Write(src[written : i+1])
+	return i + 1, out.Bytes()
+}
+
+// Escapes the characters of an attribute value that the tokenizer would otherwise read as the end of the value or as
+// a character reference.
+var attrValueEscaper = strings.NewReplacer(`&`, `&amp;`, `"`, `&quot;`)
+
+// Finds the end of the embedded code starting with the brace at start, ex. {c.items.Map(func() { ... })}, skipping
+// braces in Go strings. Returns the index after the matching brace or -1 if there is none.
+func matchingBrace(src []byte, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(src); i++ {
+		c := src[i]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '`':
+			quote = c
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
@@ -313,3 +313,94 @@ func (c *CustomInputPage) outputTable() vecty.ComponentOrHTML {
 			</div>
 		</div>`, string(htmlSrc))
 }
+
+func TestParseHtml_KeepsTheRawAttributeNames(t *testing.T) {
+	tag, err := ParseHtmlString(`<svg viewBox="0 0 10 10" class='a b' {...c.Markup} data-X=value disabled/>`)
+	require.Nil(t, err)
+	var names, rawNames []string
+	for _, attr := range tag.Attr {
+		names = append(names, attr.Name)
+		rawNames = append(rawNames, attr.RawName)
+	}
+	require.Equal(t, []string{"viewbox", "class", "{...c.markup}", "data-x", "disabled"}, names)
+	require.Equal(t, []string{"viewBox", "class", "{...c.Markup}", "data-X", "disabled"}, rawNames)
+}
+
+func TestParseHtml_KeepsTheRawTagNames(t *testing.T) {
+	tag, err := ParseHtmlString(`<svg><linearGradient id="a"></linearGradient><clipPath/></svg>`)
+	require.Nil(t, err)
+	require.Equal(t, "svg", tag.RawTagName)
+	require.Equal(t, "lineargradient", tag.Children[0].TagName)
+	require.Equal(t, "linearGradient", tag.Children[0].RawTagName)
+	require.Equal(t, "clipPath", tag.Children[1].RawTagName)
+}
+
+func TestParseHtml_TracksTheLinesOfTagsAndText(t *testing.T) {
+	tag, err := ParseHtmlString(`<div>
+	<p
+		class="a">first</p>
+
+	{second}
+	<br/></div>`)
+	require.Nil(t, err)
+	require.Equal(t, 1, tag.Line)
+	require.Equal(t, 2, tag.Children[0].Line)
+	require.Equal(t, 3, tag.Children[0].Children[0].Line)
+	require.Equal(t, 5, tag.Children[1].Line)
+	require.Equal(t, 6, tag.Children[2].Line)
+}
+
+func TestParseHtml_KeepsComments(t *testing.T) {
+	tag, err := ParseHtmlString(`<div><!-- first --><p>text</p></div>`)
+	require.Nil(t, err)
+	require.Equal(t, &TagOrText{Text: "first", IsComment: true, Line: 1}, tag.Children[0])
+	require.Equal(t, "p", tag.Children[1].TagName)
+}
+
+func TestParseHtml_KeepsTemplatesNestedInEmbedsAsText(t *testing.T) {
+	tag, err := ParseHtmlString(`<ul>{c.ok && <li class="a">it's {c.name}</li>} <b>after</b></ul>`)
+	require.Nil(t, err)
+	require.Len(t, tag.Children, 2)
+	require.Equal(t, `{c.ok && <li class="a">it's {c.name}</li>}`, tag.Children[0].Text)
+	require.Equal(t, "b", tag.Children[1].TagName)
+}
+
+func TestParseHtml_EndsUnclosedEmbedsAtTheEndTag(t *testing.T) {
+	tag, err := ParseHtmlString(`<p>Items: {n</p>`)
+	require.Nil(t, err)
+	require.Equal(t, "Items: {n", tag.Children[0].Text)
+}
+
+func TestParseHtml_ReadsUnquotedEmbeddedCodeToTheMatchingBrace(t *testing.T) {
+	r := bytes.NewReader([]byte(`<button click={c.n++; c.save(a > b, "}")} class="x" Title={&c.t}>add</button> + rest`))
+	tag, htmlSrc, err := ParseHtml(r)
+	require.Nil(t, err)
+	require.Equal(t, []*Attr{
+		{Name: "click", RawName: "click", Value: `{c.n++; c.save(a > b, "}")}`, HasValue: true},
+		{Name: "class", RawName: "class", Value: "x", HasValue: true},
+		{Name: "title", RawName: "Title", Value: "{&c.t}", HasValue: true},
+	}, tag.Attr)
+	require.Equal(t, "add", tag.Children[0].Text)
+	require.Equal(t, `<button click={c.n++; c.save(a > b, "}")} class="x" Title={&c.t}>add</button>`, string(htmlSrc))
+	rest, _ := io.ReadAll(r)
+	require.Equal(t, " + rest", string(rest))
+}
+
+func TestParseHtml_ReadsMarkupSpreadsToTheMatchingBrace(t *testing.T) {
+	tag, err := ParseHtmlString(`<button {...c.Markup(a, b > 1)} class="x"/>`)
+	require.Nil(t, err)
+	require.Equal(t, []*Attr{
+		{Name: "{...c.markup(a, b > 1)}", RawName: "{...c.Markup(a, b > 1)}"},
+		{Name: "class", RawName: "class", Value: "x", HasValue: true},
+	}, tag.Attr)
+}
+
+func TestParseHtml_TracksIfAttributesHaveAValue(t *testing.T) {
+	tag, err := ParseHtmlString(`<input disabled value="" Title = '' checked/>`)
+	require.Nil(t, err)
+	var hasValue []bool
+	for _, attr := range tag.Attr {
+		hasValue = append(hasValue, attr.HasValue)
+	}
+	require.Equal(t, []bool{false, true, true, false}, hasValue)
+}
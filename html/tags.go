@@ -8,15 +8,28 @@ import (
 )
 
 type Attr struct {
-	Name  string
-	Value string
+	// Name is the lower case attribute name.
+	Name string
+	// RawName is the attribute name as written in the source, ex. viewBox rather than viewbox.
+	RawName string
+	Value   string
+	// HasValue is false when the attribute is written without a value, ex. <input disabled>, rather than with an
+	// empty one, ex. <input value="">.
+	HasValue bool
 }
 
 type TagOrText struct {
-	TagName  string
-	Text     string
-	Attr     []*Attr
-	Children []*TagOrText
+	// TagName is the lower case tag name.
+	TagName string
+	// RawTagName is the tag name as written in the source, ex. linearGradient rather than lineargradient.
+	RawTagName string
+	Text       string
+	Attr       []*Attr
+	Children   []*TagOrText
+	// Line is the line the tag or text starts on, counting from 1 at the start of the parsed html.
+	Line int
+	// IsComment is true if this is an html comment, ex. <!-- text -->, in which case Text is the comment text.
+	IsComment bool
 }
 
 func (t *TagOrText) AppendChild(child *TagOrText) {
@@ -32,7 +45,9 @@ func (t *TagOrText) DebugString() string {
 func (t *TagOrText) debugString(w io.Writer, depth string) {
 	if t.TagName == "" {
 		fmt.Fprint(w, depth)
-		if t.IsGoCodeEmbed() {
+		if t.IsComment {
+			fmt.Fprintln(w, "comment:"+t.Text)
+		} else if t.IsGoCodeEmbed() {
 			fmt.Fprintln(w, "embed:"+t.Text)
 		} else {
 			fmt.Fprintln(w, t.Text)
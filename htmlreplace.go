@@ -22,8 +22,9 @@ type htmlReplaceState struct {
 func sourceHtmlReplace(ht htmlTracker, w io.Writer, src *bytes.Reader) (htmlTracker, error) {
 	rs := htmlReplaceState{ht: ht, r: src}
 	rs.stateFn = rs.htmlStateReadChars
+	lw := &lineCountingWriter{w: w}
 	for {
-		if done, err := rs.stateFn(w); err != nil {
+		if done, err := rs.stateFn(lw); err != nil {
 			return nil, err
 		} else if done {
 			return rs.ht, nil
@@ -192,6 +193,10 @@ func (rs *htmlReplaceState) htmlStateReadHtml(w io.Writer) (bool, error) {
 			return false, err
 		}
 	} else {
+		// The html is written back out unchanged, so the lines written so far are the lines of the source before it.
+		if lw, ok := w.(*lineCountingWriter); ok {
+			offsetLines(tag, lw.lines)
+		}
 		var tagId htmlTrackingId
 		rs.ht, tagId = rs.ht.add(tag)
 		_, err = fmt.Fprintf(w, "tvecty.Html(%d, `%s`)", tagId, htmlSrc)
@@ -200,6 +205,26 @@ func (rs *htmlReplaceState) htmlStateReadHtml(w io.Writer) (bool, error) {
 	return false, err
 }
 
+// Offsets the lines of the tag and its children, so they are relative to the start of the source file rather than
+// the start of the html.
+func offsetLines(tag *html.TagOrText, offset int) {
+	tag.Line += offset
+	for _, child := range tag.Children {
+		offsetLines(child, offset)
+	}
+}
+
+// Counts the lines written to the underlying writer.
+type lineCountingWriter struct {
+	w     io.Writer
+	lines int
+}
+
+func (lw *lineCountingWriter) Write(p []byte) (int, error) {
+	lw.lines += bytes.Count(p, []byte("\n"))
+	return lw.w.Write(p)
+}
+
 func writeRune(w io.Writer, c rune) error {
 	_, err := fmt.Fprintf(w, "%c", c)
 	return err
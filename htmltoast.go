@@ -1,13 +1,70 @@
 package tvecty
 
 import (
+	"fmt"
 	"github.com/dave/dst"
 	"github.com/mdev5000/tvecty/html"
 	"strings"
 )
 
+const (
+	svgNamespace    = "http://www.w3.org/2000/svg"
+	mathMLNamespace = "http://www.w3.org/1998/Math/MathML"
+	xlinkNamespace  = "http://www.w3.org/1999/xlink"
+)
+
 var tagTranslations map[string]string
 
+// Attributes that reflect the live state of an element and therefore need to be set as DOM properties (via
+// vecty.Property) rather than attributes, ex. the value attribute of an input only sets its initial value. Maps the
+// attribute name to the property name. Boolean properties, such as checked, are in booleanAttributes.
+var propertyTranslations = map[string]string{
+	"currenttime":   "currentTime",
+	"defaultvalue":  "defaultValue",
+	"indeterminate": "indeterminate",
+	"playbackrate":  "playbackRate",
+	"scrollleft":    "scrollLeft",
+	"scrolltop":     "scrollTop",
+	"selectedindex": "selectedIndex",
+	"value":         "value",
+	"volume":        "volume",
+}
+
+// Boolean html attributes, these are either on or off rather than having a value. Maps the attribute name to the
+// property that reflects it, or an empty string if it is only an attribute.
+var booleanAttributes = map[string]string{
+	"allowfullscreen": "allowFullscreen",
+	"async":           "async",
+	"autofocus":       "autofocus",
+	"autoplay":        "autoplay",
+	"checked":         "checked",
+	"controls":        "controls",
+	"default":         "default",
+	"defer":           "defer",
+	"disabled":        "disabled",
+	"formnovalidate":  "formNoValidate",
+	"hidden":          "hidden",
+	"inert":           "inert",
+	"ismap":           "isMap",
+	"itemscope":       "",
+	"loop":            "loop",
+	"multiple":        "multiple",
+	"muted":           "muted",
+	"nomodule":        "noModule",
+	"novalidate":      "noValidate",
+	"open":            "open",
+	"playsinline":     "playsInline",
+	"readonly":        "readOnly",
+	"required":        "required",
+	"reversed":        "reversed",
+	"selected":        "selected",
+}
+
+// The values other than on or off of boolean attributes that are enumerated, ex. hidden="until-found".
+var enumeratedAttributeValues = map[string]map[string]bool{
+	"hidden": {"until-found": true},
+}
+
 func init() {
 	// Setup the common tag translations for the vecty function equivalents.
 	//
@@ -93,109 +150,574 @@ func htmlToDst(htmlRaw string) (dst.Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	exprs, err := tagToAst(nil, rootTag)
+	exprs, err := tagToAst(newTagContext(nil), nil, rootTag)
 	if err != nil {
 		return nil, err
 	}
 	return exprs[0], nil
 }
 
-func tagsToAst(existing []dst.Expr, tags []*html.TagOrText) ([]dst.Expr, error) {
+func tagsToAst(ctx *tagContext, existing []dst.Expr, tags []*html.TagOrText) ([]dst.Expr, error) {
+	out, _, err := tagsToAstWithComments(ctx, existing, tags)
+	return out, err
+}
+
+// Converts the tags like tagsToAst, but also returns the comments that could not be placed because the tags have no
+// code, ex. <div><!-- todo --></div>, so the caller can place them.
+func tagsToAstWithComments(ctx *tagContext, existing []dst.Expr, tags []*html.TagOrText) ([]dst.Expr, []string, error) {
 	if len(tags) == 0 {
-		return existing, nil
+		return existing, nil, nil
 	}
 	out := make([]dst.Expr, len(existing), len(existing)+len(tags))
 	copy(out, existing)
+	// Html comments are placed before the code of the next tag, or after the last argument when there isn't one.
+	var comments []string
 	for _, tag := range tags {
+		if tag.IsComment {
+			if !ctx.stripComments {
+				comments = append(comments, goComments(tag.Text)...)
+			}
+			continue
+		}
+		before := len(out)
 		var err error
-		out, err = tagToAst(out, tag)
+		out, err = tagToAst(ctx, out, tag)
 		if err != nil {
-			return out, err
+			return out, nil, err
+		}
+		if len(comments) > 0 && len(out) > before {
+			decs := out[before].Decorations()
+			decs.Before = dst.NewLine
+			decs.Start.Append(comments...)
+			comments = nil
+		}
+	}
+	if len(comments) > 0 && len(out) > 0 {
+		decs := out[len(out)-1].Decorations()
+		decs.After = dst.NewLine
+		decs.End.Append("\n")
+		decs.End.Append(comments...)
+		comments = nil
+	}
+	return out, comments, nil
+}
+
+// Checks if the tags have content other than comments.
+func hasContent(tags []*html.TagOrText) bool {
+	for _, tag := range tags {
+		if !tag.IsComment {
+			return true
 		}
 	}
-	return out, nil
+	return false
 }
 
-func tagToAst(existing []dst.Expr, tag *html.TagOrText) ([]dst.Expr, error) {
+// Converts the text of an html comment into Go line comments.
+func goComments(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		out = append(out, strings.TrimRight("// "+strings.TrimSpace(line), " "))
+	}
+	return out
+}
+
+func tagToAst(ctx *tagContext, existing []dst.Expr, tag *html.TagOrText) ([]dst.Expr, error) {
+	if tag.IsComment {
+		// Comments are placed by tagsToAst.
+		return existing, nil
+	}
+	// Not restored after the tag is converted, so on error it is the line of the tag that caused it.
+	ctx.line = tag.Line
 	// Tagname is empty if the tag is a text tag
 	// Ex. <div>{embed} and more</div>
 	// "{embed} and more" would be a text tag.
 	if tag.TagName == "" {
 		var err error
-		existing, err = parseTagTextValue(existing, tag.Text)
+		existing, err = parseTagTextValue(ctx, existing, tag.Text)
 		if err != nil {
 			return nil, err
 		}
 	} else {
+		parentNamespace, parentElement := ctx.namespace, ctx.element
+		defer func() { ctx.namespace, ctx.element = parentNamespace, parentElement }()
+		ctx.namespace = tagNamespace(parentNamespace, tag.TagName)
+		ctx.element = ctx.elements[tag.TagName]
+
+		if tag.TagName == "t" && ctx.namespace == "" {
+			expr, err := parseTranslateElement(ctx, tag)
+			if err != nil {
+				return nil, err
+			}
+			return append(existing, expr), nil
+		}
+
+		if ctx.namespace == "" {
+			switch {
+			case tag.TagName == "include":
+				expr, err := parseIncludeElement(ctx, tag)
+				if err != nil {
+					return nil, err
+				}
+				return append(existing, expr), nil
+			case tag.TagName == "layout":
+				expr, err := parseLayoutElement(ctx, tag)
+				if err != nil {
+					return nil, err
+				}
+				return append(existing, expr), nil
+			case tag.TagName == "fill":
+				return nil, fmt.Errorf("the <fill> element must be a child of a <layout>")
+			case tag.TagName == "slot" && ctx.slots != nil:
+				return slotToAst(ctx, existing, tag)
+			}
+		}
+
+		if tag.TagName == "let" && ctx.namespace == "" {
+			expr, err := parseLetElement(ctx, tag)
+			if err != nil {
+				return nil, err
+			}
+			return append(existing, expr), nil
+		}
+
+		if isScopedStyle(tag) {
+			// The css is written to a separate file, see collectScopedStyles.
+			return existing, nil
+		}
+
 		tagExists, vectyPkg, vectyFn := tagNameToVectyElem(tag.TagName)
 		var args []dst.Expr
+		var markup []dst.Expr
 		var err error
+		if ctx.namespace != "" {
+			// Elements outside of the html namespace are never translated, since they can share names with html
+			// elements (ex. <a> and <title> in svg), and keep the case of their name (ex. <linearGradient>).
+			tagExists = false
+			markup = append(markup, simpleCallExpr("vecty", "Namespace", []dst.Expr{stringLit(ctx.namespace)}))
+		}
+		if ctx.scopeClass != "" && !rawTextElements[tag.TagName] {
+			markup = append(markup, simpleCallExpr("vecty", "Class", []dst.Expr{stringLit(ctx.scopeClass)}))
+		}
 		if !tagExists {
-			args = append(args, stringLit(tag.TagName))
+			args = append(args, stringLit(rawTagName(tag)))
 			vectyPkg = "vecty"
 			vectyFn = "Tag"
 		}
-		args, err = parseTagAttributes(args, tag)
+		unsafeExpr, err := unsafeHTMLContent(ctx, tag)
 		if err != nil {
 			return nil, err
 		}
-		args, err = tagsToAst(args, tag.Children)
+		if unsafeExpr != nil {
+			markup = append(markup, unsafeExpr)
+		}
+		args, err = parseTagAttributes(ctx, args, tag, markup)
 		if err != nil {
 			return nil, err
 		}
-		existing = append(existing, simpleCallExpr(vectyPkg, vectyFn, args))
+		if ctx.namespace == svgNamespace && tag.TagName == "foreignobject" {
+			// The contents of a foreignObject are html.
+			ctx.namespace = ""
+		}
+		var comments []string
+		if rawTextElements[tag.TagName] {
+			args = rawTextToAst(args, tag)
+		} else if unsafeExpr == nil {
+			args, comments, err = tagsToAstWithComments(ctx, args, tag.Children)
+			if err != nil {
+				return nil, err
+			}
+		}
+		call := simpleCallExpr(vectyPkg, vectyFn, args)
+		// Comments of an element without arguments are placed within its parentheses.
+		if len(comments) > 0 {
+			call.Decs.Lparen.Append("\n")
+			call.Decs.Lparen.Append(comments...)
+		}
+		var expr dst.Expr = call
+		if hasLetAttributes(tag) {
+			stmts, err := letBindings(ctx, tag.Attr, letAttributePrefix)
+			if err != nil {
+				return nil, err
+			}
+			expr = letFunc(stmts, expr)
+		}
+		existing = append(existing, expr)
 	}
 	return existing, nil
 }
 
-func parseTagAttributes(existing []dst.Expr, tag *html.TagOrText) ([]dst.Expr, error) {
-	if len(tag.Attr) == 0 {
+// Checks if the content of a tag is an unsafe html embed, ex. <div>{unsafe:c.markdown}</div>, and if so returns the
+// vecty.UnsafeHTML markup that sets it. Unsafe html must be the only content of the element, since vecty replaces the
+// contents of the element with it.
+func unsafeHTMLContent(ctx *tagContext, tag *html.TagOrText) (dst.Expr, error) {
+	if len(tag.Children) != 1 || tag.Children[0].TagName != "" {
+		return nil, nil
+	}
+	parts, err := tokenizeExpressionParts(tag.Children[0].Text)
+	if err != nil || len(parts) != 1 || !parts[0].isEmbeddedCode {
+		return nil, nil
+	}
+	m := embedModifierRegex.FindStringSubmatch(parts[0].value)
+	if len(m) == 0 || m[1] != unsafeModifier {
+		return nil, nil
+	}
+	if ctx.disallowUnsafeHTML {
+		return nil, fmt.Errorf("unsafe html is not allowed, but was used in '{%s}'", parts[0].value)
+	}
+	expr, err := parseExpression(m[2], false)
+	if err != nil {
+		return nil, err
+	}
+	return simpleCallExpr("vecty", "UnsafeHTML", []dst.Expr{expr}), nil
+}
+
+// Get the namespace of an element given the namespace of its parent, the html namespace is an empty string.
+func tagNamespace(parentNamespace, tagName string) string {
+	switch tagName {
+	case "svg":
+		return svgNamespace
+	case "math":
+		return mathMLNamespace
+	}
+	return parentNamespace
+}
+
+func rawTagName(tag *html.TagOrText) string {
+	if tag.RawTagName != "" {
+		return tag.RawTagName
+	}
+	return tag.TagName
+}
+
+// Parse the attributes of a tag into a vecty.Markup() call, markup is any additional markup to place before the
+// attributes.
+func parseTagAttributes(ctx *tagContext, existing []dst.Expr, tag *html.TagOrText, markup []dst.Expr) ([]dst.Expr, error) {
+	if len(tag.Attr) == 0 && len(markup) == 0 {
 		return existing, nil
 	}
-	markupArgs := make([]dst.Expr, 0, len(tag.Attr))
+	markupArgs := make([]dst.Expr, 0, len(markup)+len(tag.Attr))
+	markupArgs = append(markupArgs, markup...)
+	classes := classMapBuilder{}
 	for _, attr := range tag.Attr {
-		switch attr.Name {
-		case "markup":
-			attrExpr, err := parseMultipleAttributeValue(nil, attr.Value, true)
+		switch {
+		case strings.HasSuffix(attr.Name, "?"):
+			condExpr, err := parseConditionalAttribute(ctx, tag, attr)
+			if err != nil {
+				return existing, err
+			}
+			markupArgs = append(markupArgs, condExpr)
+		case attr.Name == "markup":
+			attrExpr, err := parseMultipleAttributeValue(ctx, nil, attr.Value, true)
 			if err != nil {
 				return existing, err
 			}
 			markupArgs = append(markupArgs, attrExpr...)
-		case "class":
-			attrExpr, err := parseMultipleAttributeValue(nil, attr.Value, false)
+		case attr.Name == "class":
+			attrExpr, err := classes.parseClassAttribute(ctx, attr)
 			if err != nil {
 				return existing, err
 			}
-			markupArgs = append(markupArgs, simpleCallExpr("vecty", "Class", attrExpr))
-		case "click":
-			expr, err := parseExpression(attr.Value, false)
+			if attrExpr != nil {
+				markupArgs = append(markupArgs, simpleCallExpr("vecty", "Class", attrExpr))
+			}
+		case strings.HasPrefix(attr.Name, "class:"):
+			if err := classes.parseClassToggle(attr); err != nil {
+				return existing, err
+			}
+		case attr.Name == "style":
+			styleExprs, err := parseStyleAttribute(ctx, attr)
+			if err != nil {
+				return existing, err
+			}
+			markupArgs = append(markupArgs, styleExprs...)
+		case strings.HasPrefix(attr.Name, "style:"):
+			styleExpr, err := parseStylePropertyAttribute(attr)
 			if err != nil {
 				return existing, err
 			}
-			markupArgs = append(markupArgs, simpleCallExpr("event", "Click", []dst.Expr{expr}))
-		case "blur":
-			expr, err := parseExpression(attr.Value, false)
+			markupArgs = append(markupArgs, styleExpr)
+		case strings.HasPrefix(attr.Name, "{..."):
+			spreadExpr, err := parseSpreadAttribute(ctx, attr)
 			if err != nil {
 				return existing, err
 			}
-			markupArgs = append(markupArgs, simpleCallExpr("event", "Blur", []dst.Expr{expr}))
-		case "change":
-			expr, err := parseExpression(attr.Value, false)
+			markupArgs = append(markupArgs, spreadExpr)
+		case strings.HasPrefix(attr.Name, letAttributePrefix):
+			// The variables are declared around the element, see letBindings.
+		case strings.HasPrefix(attr.Name, "bind:"):
+			bindExprs, err := parseBindAttribute(ctx, tag, attr)
 			if err != nil {
 				return existing, err
 			}
-			markupArgs = append(markupArgs, simpleCallExpr("event", "Change", []dst.Expr{expr}))
+			markupArgs = append(markupArgs, bindExprs...)
 		default:
-			attrExpr, err := parseSingleAttributeValue([]dst.Expr{stringLit(attr.Name)}, attr.Value)
+			attrExpr, err := parseNamedAttribute(ctx, attr)
 			if err != nil {
 				return existing, err
 			}
-			markupArgs = append(markupArgs, simpleCallExpr("vecty", "Attribute", attrExpr))
+			if attrExpr != nil {
+				markupArgs = append(markupArgs, attrExpr)
+			}
 		}
 	}
+	if classes.classMap != nil {
+		markupArgs = append(markupArgs, classes.classMap)
+	}
 	return append(existing, simpleCallExpr("vecty", "Markup", markupArgs)), nil
 }
 
+// Parse an attribute that is not handled by name in parseTagAttributes. Events are bound with the vecty event
+// package, data-* attributes with string values are set with vecty.Data, known DOM properties (see propertyTranslations) are set as
+// properties and everything else is set as an attribute. Aria attributes are validated against the WAI-ARIA
+// attributes. Either a property or an attribute can be forced using the prop: and attr: prefixes, ex.
+// prop:scrollTop={top} or attr:value="initial".
+//
+// For elements outside of the html namespace (ex. svg) the html properties and boolean attributes do not apply and
+// the case of attribute names is kept (ex. viewBox).
+func parseNamedAttribute(ctx *tagContext, attr *html.Attr) (dst.Expr, error) {
+	name := attr.Name
+	if ctx.namespace != "" {
+		name = attr.RawName
+	}
+	vectyFn := "Attribute"
+	switch {
+	case strings.HasPrefix(attr.Name, "prop:"):
+		// Property names are case sensitive, ex. scrollTop.
+		name = attr.RawName[len("prop:"):]
+		vectyFn = "Property"
+	case strings.HasPrefix(attr.Name, "attr:"):
+		name = name[len("attr:"):]
+	case ctx.namespace != "" && attr.Name == "xmlns":
+		// The namespace is set using vecty.Namespace.
+		if attr.Value != ctx.namespace {
+			return nil, fmt.Errorf("xmlns '%s' does not match the namespace of the element '%s'", attr.Value, ctx.namespace)
+		}
+		return nil, nil
+	case ctx.namespace != "" && attr.Name == "xmlns:xlink" && attr.Value == xlinkNamespace:
+		// Only needed for xlink:href, which is set as href.
+		return nil, nil
+	case ctx.namespace != "" && attr.Name == "xlink:href":
+		// vecty cannot set namespaced attributes, but xlink:href is replaced by href in SVG 2.
+		name = "href"
+	case ctx.namespace != "" && (strings.HasPrefix(attr.Name, "xmlns:") || strings.HasPrefix(attr.Name, "xml:") ||
+		strings.HasPrefix(attr.Name, "xlink:")):
+		// Setting these without their namespace would not have any effect, so they are an error rather than dropped.
+		hint := ""
+		if attr.Name == "xml:lang" {
+			hint = ", use lang instead"
+		}
+		return nil, fmt.Errorf("namespaced attribute '%s' is not supported, vecty cannot set attributes in a namespace%s", attr.RawName, hint)
+	default:
+		ev, err := parseEventAttributeName(attr.Name, ctx.element)
+		if err != nil {
+			return nil, err
+		}
+		if ev != nil {
+			return ev.toAst(ctx, attr.Value)
+		}
+		if ctx.element != nil {
+			expr, err := parseCustomElementAttribute(ctx, ctx.element, attr)
+			if expr != nil || err != nil {
+				return expr, err
+			}
+			if err := checkCustomElementAttribute(ctx.element, attr.Name); err != nil {
+				return nil, err
+			}
+		}
+		if strings.HasPrefix(attr.Name, "data-") {
+			// vecty.Data only takes strings, so other values, ex. data-id={c.id} with an int, are set as attributes.
+			if isStringValue(attr.Value) {
+				vectyFn = "Data"
+				name = datasetKey(attr.Name[len("data-"):])
+			}
+			break
+		}
+		if strings.HasPrefix(attr.Name, "aria-") {
+			if err := validateAriaAttribute(attr.Name, attr.Value); err != nil {
+				return nil, err
+			}
+		}
+		if ctx.namespace != "" {
+			break
+		}
+		if propName, isBool := booleanAttributes[name]; isBool {
+			return parseBooleanAttribute(name, propName, attr.Value)
+		}
+		if propName, isProp := propertyTranslations[name]; isProp {
+			name = propName
+			vectyFn = "Property"
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing name in attribute '%s'", attr.Name)
+	}
+	attrExpr, err := parseSingleAttributeValue(ctx, []dst.Expr{stringLit(name)}, attr.Value)
+	if err != nil {
+		return nil, err
+	}
+	attrExpr[1] = ctx.attributeValueExpr(attr, attrExpr[1])
+	return simpleCallExpr("vecty", vectyFn, attrExpr), nil
+}
+
+// Converts the name of a data-* attribute without the data- prefix into its key in the element's dataset, ex. user-id
+// is userId. The dataset rejects keys with a - followed by a lower case letter.
+func datasetKey(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '-' && i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z' {
+			b.WriteByte(name[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// Parse a conditional attribute, ex. click?="{c.enabled, c.onClick}", into vecty.MarkupIf(c.enabled, ...) where the
+// markup is whatever the attribute without the ? would be given the remaining value, in this case
+// event.Click(c.onClick).
+func parseConditionalAttribute(ctx *tagContext, tag *html.TagOrText, attr *html.Attr) (dst.Expr, error) {
+	invalid := func() error {
+		return fmt.Errorf("conditional attribute '%s' must be in the form {condition, value}, but was '%s'", attr.RawName, attr.Value)
+	}
+	parts, err := tokenizeExpressionParts(attr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != 1 || !parts[0].isEmbeddedCode {
+		return nil, invalid()
+	}
+	condAndValue := splitTopLevel(parts[0].value, ',')
+	if len(condAndValue) < 2 {
+		return nil, invalid()
+	}
+	condStr := strings.TrimSpace(condAndValue[0])
+	valueStr := strings.TrimSpace(strings.Join(condAndValue[1:], ","))
+	if condStr == "" || valueStr == "" {
+		return nil, invalid()
+	}
+	cond, err := parseExpression(condStr, false)
+	if err != nil {
+		return nil, err
+	}
+	condAttr := &html.Attr{
+		Name:     strings.TrimSuffix(attr.Name, "?"),
+		RawName:  strings.TrimSuffix(attr.RawName, "?"),
+		Value:    "{" + valueStr + "}",
+		HasValue: true,
+	}
+	markupExprs, err := parseTagAttributes(ctx, nil, &html.TagOrText{TagName: tag.TagName, Attr: []*html.Attr{condAttr}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The attribute is returned wrapped in vecty.Markup(...), the arguments of which are all vecty.Applyer.
+	markup := markupExprs[0].(*dst.CallExpr).Args
+	if len(markup) == 0 {
+		return nil, fmt.Errorf("conditional attribute '%s' does not produce any markup", attr.RawName)
+	}
+	if ctx.typeCheck {
+		for _, m := range markup {
+			ctx.embeds = append(ctx.embeds, &untypedEmbed{expr: m, src: valueStr, line: ctx.line, kind: embedApplyer})
+		}
+	}
+	return simpleCallExpr("vecty", "MarkupIf", append([]dst.Expr{cond}, markup...)), nil
+}
+
+// Parse a markup spread attribute, ex. {...c.Markup} where c.Markup is a []vecty.Applyer or a vecty.MarkupList, so it
+// can be passed along with the other markup of the element. A vecty.Markup(a, b) call is already an Applyer so it's
+// passed as is. When type checking, a slice becomes vecty.Markup(c.Markup...) and any other Applyer is passed as is,
+// see typeCheckEmbeds. Otherwise the type is not known, so the value is converted by a function that accepts either.
+func parseSpreadAttribute(ctx *tagContext, attr *html.Attr) (dst.Expr, error) {
+	if !strings.HasSuffix(attr.RawName, "}") || attr.Value != "" {
+		return nil, fmt.Errorf("invalid markup spread '%s', expected {...expression}", attr.RawName)
+	}
+	src := attr.RawName[len("{...") : len(attr.RawName)-1]
+	expr, err := parseExpression(src, false)
+	if err != nil {
+		return nil, err
+	}
+	if isVectyCall(expr, "Markup") {
+		expr.Decorations().Before = dst.NewLine
+		expr.Decorations().After = dst.NewLine
+		return expr, nil
+	}
+	if !ctx.typeCheck {
+		expr, err := parseExpression(fmt.Sprintf(`func(markup interface{}) vecty.Applyer {
+	if applyers, ok := markup.([]vecty.Applyer); ok {
+		return vecty.Markup(applyers...)
+	}
+	return markup.(vecty.Applyer)
+}(%s)`, src), false)
+		if err != nil {
+			return nil, err
+		}
+		expr.Decorations().Before = dst.NewLine
+		expr.Decorations().After = dst.NewLine
+		return expr, nil
+	}
+	call := simpleCallExpr("vecty", "Markup", []dst.Expr{expr})
+	call.Ellipsis = true
+	ctx.embeds = append(ctx.embeds, &untypedEmbed{expr: call, src: "..." + src, line: ctx.line, kind: embedSpread})
+	return call, nil
+}
+
+// Checks if expr is a call to a function of the vecty package, ex. vecty.Markup(a, b).
+func isVectyCall(expr dst.Expr, name string) bool {
+	call, ok := expr.(*dst.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*dst.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*dst.Ident)
+	return ok && pkg.Name == "vecty" && sel.Sel.Name == name
+}
+
+// Parse a boolean attribute, ex. <button disabled> or disabled={cond}. The attribute is on when it has no value, its
+// own name or "true" as its value. Like in html any other static value would also turn it on, so "false" is an error
+// rather than silently on, a {condition} turns it off. Boolean attributes with a matching DOM property are set using
+// the property, otherwise the attribute is only added when on, so a false condition removes it. The other values of
+// an enumerated attribute, ex. hidden="until-found", are set as is.
+func parseBooleanAttribute(name, propName, value string) (dst.Expr, error) {
+	parts, err := tokenizeExpressionParts(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) > 1 {
+		return nil, fmt.Errorf("boolean attribute '%s' must be a single condition, but was '%s'", name, value)
+	}
+	var cond dst.Expr
+	if parts[0].isEmbeddedCode {
+		cond, err = parseExpression(parts[0].value, false)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		switch parts[0].value {
+		case "", name, "true":
+			cond = dst.NewIdent("true")
+		case "false":
+			return nil, fmt.Errorf("boolean attribute '%s' is on whenever it is present, even as %s=\"false\", use %s={false} or a {condition} to turn it off", name, name, name)
+		default:
+			if enumeratedAttributeValues[name][parts[0].value] {
+				return simpleCallExpr("vecty", "Attribute", []dst.Expr{stringLit(name), stringLit(parts[0].value)}), nil
+			}
+			return nil, fmt.Errorf("invalid value '%s' for boolean attribute '%s', expected no value, true or a {condition}", value, name)
+		}
+	}
+	if propName != "" {
+		return simpleCallExpr("vecty", "Property", []dst.Expr{stringLit(propName), cond}), nil
+	}
+	attrExpr := simpleCallExpr("vecty", "Attribute", []dst.Expr{stringLit(name), stringLit("")})
+	if ident, ok := cond.(*dst.Ident); ok && ident.Name == "true" {
+		return attrExpr, nil
+	}
+	return simpleCallExpr("vecty", "MarkupIf", []dst.Expr{cond, attrExpr}), nil
+}
+
 func tagNameToVectyElem(tagName string) (bool, string, string) {
 	vectyName, found := tagTranslations[tagName]
 	return found, "elem", vectyName
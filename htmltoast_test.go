@@ -78,7 +78,7 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 }
 
 func TestHTmlToDst_ParsesCustomAttributesAsSingleValue(t *testing.T) {
-	htmlS := `<div data-ducks="this is all one argument">{s:"stuff"}</div>`
+	htmlS := `<div ducks="this is all one argument">{s:"stuff"}</div>`
 	expr, err := htmlToDst(htmlS)
 	require.NoError(t, err)
 	requireEqStr(t, tWrapExpr(t, expr), `
@@ -87,7 +87,7 @@ package thing
 func RenderThing(msg string) vecty.HTMLOrComponent {
 	elem.Div(
 		vecty.Markup(
-			vecty.Attribute("data-ducks", "this is all one argument"),
+			vecty.Attribute("ducks", "this is all one argument"),
 		),
 		vecty.Text("stuff"),
 	)
@@ -234,6 +234,31 @@ func TestHtmlToDst_HandlesSpecialTags(t *testing.T) {
 			attribute: `blur="RenderThis"`,
 			result:    "event.Blur(RenderThis)",
 		},
+		{
+			name:      "embedded handler expression",
+			attribute: `click={c.onClick}`,
+			result:    "event.Click(c.onClick)",
+		},
+		{
+			name:      "on: prefixed event handled",
+			attribute: `on:keydown={c.onKey}`,
+			result:    "event.KeyDown(c.onKey)",
+		},
+		{
+			name:      "on prefixed event handled",
+			attribute: `onmouseenter={c.onEnter}`,
+			result:    "event.MouseEnter(c.onEnter)",
+		},
+		{
+			name:      "multi word event handled",
+			attribute: `dragstart={c.onDrag}`,
+			result:    "event.DragStart(c.onDrag)",
+		},
+		{
+			name:      "event colliding with an attribute is only an event when prefixed",
+			attribute: `on:open={c.onOpen}`,
+			result:    "event.Open(c.onOpen)",
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -270,3 +295,731 @@ func RenderThing(msg string) vecty.HTMLOrComponent {
 	)
 }`)
 }
+
+func TestHtmlToDst_EventNamesCollidingWithBooleanPropertiesAreProperties(t *testing.T) {
+	htmlS := `<details open="">text</details>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Details(
+		vecty.Markup(
+			vecty.Property("open", true),
+		),
+		vecty.Text("text"),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnUnknownEvents(t *testing.T) {
+	_, err := htmlToDst(`<div on:clack={c.onClick}></div>`)
+	require.EqualError(t, err, "unknown event 'clack' in attribute 'on:clack'")
+	_, err = htmlToDst(`<div onclack={c.onClick}></div>`)
+	require.EqualError(t, err, "unknown event 'clack' in attribute 'onclack'")
+}
+
+func TestHtmlToDst_SupportsEventModifiers(t *testing.T) {
+	htmlS := `<form submit.prevent={c.onSubmit} click.stop.prevent="c.onClick"></form>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Form(
+		vecty.Markup(
+			event.Submit(c.onSubmit).PreventDefault(),
+			event.Click(c.onClick).PreventDefault().StopPropagation(),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsKeyFilterEventModifiers(t *testing.T) {
+	htmlS := `<input keydown.enter.esc={c.onKey} />`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Input(
+		vecty.Markup(
+			event.KeyDown(func(e *vecty.Event) {
+				switch e.Get("key").String() {
+				case "Enter", "Escape":
+					c.onKey(e)
+				}
+			}),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_PreventsAndStopsOnlyForFilteredKeys(t *testing.T) {
+	htmlS := `<input keydown.enter.prevent.stop={c.onKey} />`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Input(
+		vecty.Markup(
+			event.KeyDown(func(e *vecty.Event) {
+				switch e.Get("key").String() {
+				case "Enter":
+					e.Call("preventDefault")
+					e.Call("stopPropagation")
+					c.onKey(e)
+				}
+			}),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnInvalidEventModifiers(t *testing.T) {
+	_, err := htmlToDst(`<div click.enter={c.onClick}></div>`)
+	require.EqualError(t, err, "key modifier 'enter' can only be used with keyboard events, but was used in attribute 'click.enter'")
+	_, err = htmlToDst(`<div click.nope={c.onClick}></div>`)
+	require.EqualError(t, err, "unknown event modifier 'nope' in attribute 'click.nope'")
+}
+
+func TestHtmlToDst_WrapsInlineEventHandlerStatements(t *testing.T) {
+	htmlS := `<button click="{c.count++; vecty.Rerender(c)}">add</button>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Button(
+		vecty.Markup(
+			event.Click(func(e *vecty.Event) {
+				c.count++
+				vecty.Rerender(c)
+			}),
+		),
+		vecty.Text("add"),
+	)
+}`)
+}
+
+func TestHtmlToDst_WrapsUnquotedInlineEventHandlerStatements(t *testing.T) {
+	htmlS := `<button click={c.count++; vecty.Rerender(c)}>add</button>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Button(
+		vecty.Markup(
+			event.Click(func(e *vecty.Event) {
+				c.count++
+				vecty.Rerender(c)
+			}),
+		),
+		vecty.Text("add"),
+	)
+}`)
+}
+
+func TestHtmlToDst_SetsDomPropertiesAsProperties(t *testing.T) {
+	htmlS := `<input value={c.text} readonly="{c.locked}" type="text" />`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Input(
+		vecty.Markup(
+			vecty.Property("value", c.text),
+			vecty.Property("readOnly", c.locked),
+			vecty.Attribute("type", "text"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsForcingPropertiesAndAttributes(t *testing.T) {
+	htmlS := `<input attr:value="initial" prop:foo={c.foo} attr:click="notanevent" />`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Input(
+		vecty.Markup(
+			vecty.Attribute("value", "initial"),
+			vecty.Property("foo", c.foo),
+			vecty.Attribute("click", "notanevent"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_KeepsTheCaseOfPropertyNames(t *testing.T) {
+	htmlS := `<div prop:scrollTop={c.top}></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Property("scrollTop", c.top),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsTwoWayBinding(t *testing.T) {
+	htmlS := `<div>
+	<input bind:value={&c.Name} />
+	<input type="checkbox" bind:checked={&c.user.Agree} />
+	<select bind:value={&c.items[i].Choice}></select>
+</div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		elem.Input(
+			vecty.Markup(
+				vecty.Property("value", c.Name),
+				event.Input(func(e *vecty.Event) {
+					c.Name = e.Target.Get("value").String()
+					vecty.Rerender(c)
+				}),
+			),
+		),
+		elem.Input(
+			vecty.Markup(
+				vecty.Attribute("type", "checkbox"),
+				vecty.Property("checked", c.user.Agree),
+				event.Change(func(e *vecty.Event) {
+					c.user.Agree = e.Target.Get("checked").Bool()
+					vecty.Rerender(c)
+				}),
+			),
+		),
+		elem.Select(
+			vecty.Markup(
+				vecty.Property("value", c.items[i].Choice),
+				event.Change(func(e *vecty.Event) {
+					c.items[i].Choice = e.Target.Get("value").String()
+					vecty.Rerender(c)
+				}),
+			),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnInvalidBindings(t *testing.T) {
+	_, err := htmlToDst(`<div bind:value={&c.Name}></div>`)
+	require.EqualError(t, err, "binding 'bind:value' can only be used on <input>, <textarea> and <select>, but was used on <div>")
+	_, err = htmlToDst(`<textarea bind:checked={&c.Name}></textarea>`)
+	require.EqualError(t, err, "binding 'bind:checked' cannot be used on <textarea>")
+	_, err = htmlToDst(`<input bind:nope={&c.Name} />`)
+	require.EqualError(t, err, "unknown binding 'bind:nope'")
+	_, err = htmlToDst(`<input bind:value={name} />`)
+	require.EqualError(t, err, "binding 'bind:value' must point to a field of the component so it can be rerendered, ex. {&c.Name}, but was 'name'")
+}
+
+func TestHtmlToDst_MergesConditionalClassesIntoAClassMap(t *testing.T) {
+	htmlS := `<div class:selected={c.selected} class='btn {{"active": sel, "disabled": !ok}}'></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Class("btn"),
+			vecty.ClassMap{
+				"selected": c.selected,
+				"active":   sel,
+				"disabled": !ok,
+			},
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnDuplicateConditionalClasses(t *testing.T) {
+	_, err := htmlToDst(`<div class:active={a} class='{{"active": b}}'></div>`)
+	require.EqualError(t, err, `duplicate conditional class "active"`)
+}
+
+func TestHtmlToDst_KeepsTheCaseOfClassToggleNames(t *testing.T) {
+	htmlS := `<div class:isOpen={c.open}></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.ClassMap{
+				"isOpen": c.open,
+			},
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ParsesStyleDeclarations(t *testing.T) {
+	htmlS := `<div style="color: red; margin: 0 {px}; background: url(data:image/png;base64,abc); font-family: 'Inter', &quot;Helvetica&quot;" style:width={c.width}></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Style("color", "red"),
+			vecty.Style("margin", "0 "+px),
+			vecty.Style("background", "url(data:image/png;base64,abc)"),
+			vecty.Style("font-family", "'Inter', \"Helvetica\""),
+			vecty.Style("width", c.width),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_KeepsTheCaseOfCustomStyleProperties(t *testing.T) {
+	expr, err := htmlToDst(`<div style="--accentColor: blue" style:--mainColor="red" style:Width="1px"></div>`)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Style("--accentColor", "blue"),
+			vecty.Style("--mainColor", "red"),
+			vecty.Style("width", "1px"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ReportsMalformedStyleDeclarations(t *testing.T) {
+	_, err := htmlToDst(`<div style="color red; margin:; wid th: 1px"></div>`)
+	require.EqualError(t, err, `invalid style attribute 'color red; margin:; wid th: 1px':
+malformed declaration 'color red', expected 'property: value'
+missing value for property 'margin'
+invalid property 'wid th' in declaration 'wid th: 1px'`)
+}
+
+func TestHtmlToDst_SetsDataAttributesWithVectyData(t *testing.T) {
+	htmlS := `<div data-id={c.id} data-kind="row" data-user-id={s:c.userID} data-count={d:c.count} data-x-2="a"></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Attribute("data-id", c.id),
+			vecty.Data("kind", "row"),
+			vecty.Data("userId", c.userID),
+			vecty.Data("count", strconv.FormatInt(int64(c.count), 10)),
+			vecty.Data("x-2", "a"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ValidatesAriaAttributes(t *testing.T) {
+	htmlS := `<div aria-label="Close" aria-hidden={c.hidden} aria-live="polite" aria-level="2"></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Attribute("aria-label", "Close"),
+			vecty.Attribute("aria-hidden", c.hidden),
+			vecty.Attribute("aria-live", "polite"),
+			vecty.Attribute("aria-level", "2"),
+		),
+	)
+}`)
+
+	cases := []struct {
+		attribute string
+		err       string
+	}{
+		{`aria-lable="Close"`, "unknown aria attribute 'aria-lable', did you mean 'aria-label'?"},
+		{`aria-nothing="x"`, "unknown aria attribute 'aria-nothing'"},
+		{`aria-hidden="yes"`, "invalid value 'yes' for aria attribute 'aria-hidden', expected true, false or undefined"},
+		{`aria-live="loud"`, "invalid value 'loud' for aria attribute 'aria-live', expected one of assertive, off, polite"},
+		{`aria-level="two"`, "invalid value 'two' for aria attribute 'aria-level', expected an integer"},
+		{`aria-activedescendant="a b"`, "invalid value 'a b' for aria attribute 'aria-activedescendant', expected a single element id"},
+	}
+	for _, c := range cases {
+		t.Run(c.attribute, func(t *testing.T) {
+			_, err := htmlToDst(fmt.Sprintf(`<div %s></div>`, c.attribute))
+			require.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestHtmlToDst_SupportsBooleanAttributes(t *testing.T) {
+	htmlS := `<input disabled required="required" hidden={false} readonly={c.locked} itemscope formnovalidate="true" />`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Input(
+		vecty.Markup(
+			vecty.Property("disabled", true),
+			vecty.Property("required", true),
+			vecty.Property("hidden", false),
+			vecty.Property("readOnly", c.locked),
+			vecty.Attribute("itemscope", ""),
+			vecty.Property("formNoValidate", true),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_RemovesAttributeOnlyBooleanAttributesWhenFalse(t *testing.T) {
+	htmlS := `<div itemscope={c.scoped} ismap={false}></div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.MarkupIf(c.scoped,
+				vecty.Attribute("itemscope", ""),
+			),
+			vecty.Property("isMap", false),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnInvalidBooleanAttributeValues(t *testing.T) {
+	_, err := htmlToDst(`<input disabled="yes" />`)
+	require.EqualError(t, err, "invalid value 'yes' for boolean attribute 'disabled', expected no value, true or a {condition}")
+	_, err = htmlToDst(`<input disabled="false" />`)
+	require.EqualError(t, err, `boolean attribute 'disabled' is on whenever it is present, even as disabled="false", use disabled={false} or a {condition} to turn it off`)
+}
+
+func TestHtmlToDst_PassesEnumeratedBooleanAttributeValuesThrough(t *testing.T) {
+	expr, err := htmlToDst(`<div hidden="until-found"></div>`)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.Attribute("hidden", "until-found"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsMarkupSpreads(t *testing.T) {
+	htmlS := `<button {...c.Markup} class="btn">text</button>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Button(
+		vecty.Markup(
+			func(markup interface{}) vecty.Applyer {
+				if applyers, ok := markup.([]vecty.Applyer); ok {
+					return vecty.Markup(applyers...)
+				}
+				return markup.(vecty.Applyer)
+			}(c.Markup),
+			vecty.Class("btn"),
+		),
+		vecty.Text("text"),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsMarkupListSpreadsAndSpacedSpreads(t *testing.T) {
+	htmlS := `<button {...vecty.Markup(c.a, c.b)} {...c.Markup(a, b)}>text</button>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Button(
+		vecty.Markup(
+			vecty.Markup(c.a, c.b),
+			func(markup interface{}) vecty.Applyer {
+				if applyers, ok := markup.([]vecty.Applyer); ok {
+					return vecty.Markup(applyers...)
+				}
+				return markup.(vecty.Applyer)
+			}(c.Markup(a, b)),
+		),
+		vecty.Text("text"),
+	)
+}`)
+}
+
+func TestHtmlToDst_SupportsConditionalAttributes(t *testing.T) {
+	htmlS := `<button click?="{c.enabled, c.onClick}" title?='{c.showTitle, "Save"}' markup?="{c.active, c.activeMarkup}"></button>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Button(
+		vecty.Markup(
+			vecty.MarkupIf(c.enabled,
+				event.Click(c.onClick),
+			),
+			vecty.MarkupIf(c.showTitle,
+				vecty.Attribute("title", "Save"),
+			),
+			vecty.MarkupIf(c.active, c.activeMarkup),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnInvalidConditionalAttributes(t *testing.T) {
+	_, err := htmlToDst(`<button click?={c.onClick}></button>`)
+	require.EqualError(t, err, "conditional attribute 'click?' must be in the form {condition, value}, but was '{c.onClick}'")
+}
+
+func TestHtmlToDst_SetsTheSvgNamespaceOnSvgElements(t *testing.T) {
+	htmlS := `<div>
+	<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10" class="icon">
+		<linearGradient id="g"></linearGradient>
+		<a xlink:href="#target"><title>Link</title></a>
+		<path d={c.path} stroke-width="2" click={c.onClick} />
+		<foreignObject><p>html</p></foreignObject>
+	</svg>
+</div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Tag("svg",
+			vecty.Markup(
+				vecty.Namespace("http://www.w3.org/2000/svg"),
+				vecty.Attribute("viewBox", "0 0 10 10"),
+				vecty.Class("icon"),
+			),
+			vecty.Tag("linearGradient",
+				vecty.Markup(
+					vecty.Namespace("http://www.w3.org/2000/svg"),
+					vecty.Attribute("id", "g"),
+				),
+			),
+			vecty.Tag("a",
+				vecty.Markup(
+					vecty.Namespace("http://www.w3.org/2000/svg"),
+					vecty.Attribute("href", "#target"),
+				),
+				vecty.Tag("title",
+					vecty.Markup(
+						vecty.Namespace("http://www.w3.org/2000/svg"),
+					),
+					vecty.Text("Link"),
+				),
+			),
+			vecty.Tag("path",
+				vecty.Markup(
+					vecty.Namespace("http://www.w3.org/2000/svg"),
+					vecty.Attribute("d", c.path),
+					vecty.Attribute("stroke-width", "2"),
+					event.Click(c.onClick),
+				),
+			),
+			vecty.Tag("foreignObject",
+				vecty.Markup(
+					vecty.Namespace("http://www.w3.org/2000/svg"),
+				),
+				elem.Paragraph(
+					vecty.Text("html"),
+				),
+			),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_ErrorsOnNamespacedSvgAttributes(t *testing.T) {
+	cases := []struct {
+		html string
+		err  string
+	}{
+		{`<svg xmlns="http://www.w3.org/1999/xhtml"></svg>`,
+			"xmlns 'http://www.w3.org/1999/xhtml' does not match the namespace of the element 'http://www.w3.org/2000/svg'"},
+		{`<svg xmlns:ev="http://www.w3.org/2001/xml-events"></svg>`,
+			"namespaced attribute 'xmlns:ev' is not supported, vecty cannot set attributes in a namespace"},
+		{`<svg><text xml:lang="en">a</text></svg>`,
+			"namespaced attribute 'xml:lang' is not supported, vecty cannot set attributes in a namespace, use lang instead"},
+		{`<svg><a xlink:title="Home"></a></svg>`,
+			"namespaced attribute 'xlink:title' is not supported, vecty cannot set attributes in a namespace"},
+	}
+	for _, tc := range cases {
+		_, err := htmlToDst(tc.html)
+		require.EqualError(t, err, tc.err)
+	}
+}
+
+func TestHtmlToDst_SetsTheMathMLNamespaceOnMathElements(t *testing.T) {
+	htmlS := `<math display="block"><mi>x</mi></math>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	vecty.Tag("math",
+		vecty.Markup(
+			vecty.Namespace("http://www.w3.org/1998/Math/MathML"),
+			vecty.Attribute("display", "block"),
+		),
+		vecty.Tag("mi",
+			vecty.Markup(
+				vecty.Namespace("http://www.w3.org/1998/Math/MathML"),
+			),
+			vecty.Text("x"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_SetsUnsafeHtmlAsMarkup(t *testing.T) {
+	htmlS := `<div class="markdown">
+	{unsafe:c.renderedMarkdown}
+</div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		vecty.Markup(
+			vecty.UnsafeHTML(c.renderedMarkdown),
+			vecty.Class("markdown"),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_UnsafeHtmlMustBeTheOnlyContent(t *testing.T) {
+	_, err := htmlToDst(`<div>Intro {unsafe:c.html}</div>`)
+	require.EqualError(t, err, "the unsafe modifier must be the only content of an element, but was used in 'unsafe:c.html'")
+
+	_, err = htmlToDst(`<div title={unsafe:c.html}></div>`)
+	require.EqualError(t, err, "the unsafe modifier must be the only content of an element, but was used in 'unsafe:c.html'")
+}
+
+func TestHtmlToDst_TreatsScriptAndStyleContentAsRawText(t *testing.T) {
+	htmlS := `<div>
+	<style>.a { color: red; }</style>
+	<script>
+		if (a) {
+			b()
+		}
+	</script>
+</div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), "\n"+`
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		elem.Style(
+			vecty.Text(".a { color: red; }"),
+		),
+		elem.Script(
+			vecty.Text(`+"`"+`if (a) {
+			b()
+		}`+"`"+`),
+		),
+	)
+}`)
+}
+
+func TestHtmlToDst_KeepsHtmlCommentsAsGoComments(t *testing.T) {
+	htmlS := `<div>
+	<!-- The title -->
+	<h1>Title</h1>
+	{/* template comments are never emitted */}
+	<p>Body {/* multi
+		line */}</p>
+	<!--
+		Trailing
+	-->
+</div>`
+	expr, err := htmlToDst(htmlS)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Div(
+		// The title
+		elem.Heading1(
+			vecty.Text("Title"),
+		),
+		elem.Paragraph(
+			vecty.Text("Body"),
+		),
+		// Trailing
+	)
+}`)
+}
+
+func TestHtmlToDst_KeepsTheCommentsOfElementsWithoutContent(t *testing.T) {
+	expr, err := htmlToDst(`<section><div><!-- todo --></div><p>x</p></section>`)
+	require.NoError(t, err)
+	requireEqStr(t, tWrapExpr(t, expr), `
+package thing
+
+func RenderThing(msg string) vecty.HTMLOrComponent {
+	elem.Section(
+		elem.Div(
+		// todo
+		),
+		elem.Paragraph(
+			vecty.Text("x"),
+		),
+	)
+}`)
+}
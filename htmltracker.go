@@ -20,12 +20,12 @@ func (h htmlTracker) add(tag *html.TagOrText) (htmlTracker, htmlTrackingId) {
 	return out, len(out)
 }
 
-func (h htmlTracker) parseAll() (htmlTrackerParsed, error) {
+func (h htmlTracker) parseAll(ctx *tagContext) (htmlTrackerParsed, error) {
 	out := make(htmlTrackerParsed, len(h)+1)
 	for i, tag := range h {
-		exprs, err := tagToAst(nil, tag)
+		exprs, err := tagToAst(ctx, nil, tag)
 		if err != nil {
-			return nil, err
+			return nil, &PositionError{Filename: ctx.filename, Line: ctx.line, Err: err}
 		}
 		if len(exprs) == 0 {
 			panic("exprs should never be empty")
@@ -0,0 +1,385 @@
+package tvecty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Attributes whose static values are shown to the user, and so are translated.
+var translatableAttributes = map[string]bool{
+	"alt":         true,
+	"aria-label":  true,
+	"placeholder": true,
+	"title":       true,
+}
+
+// Translation configures translating the static text of templates. Each piece of text is replaced by a call to Func
+// with the text as the message ID, ex. <p>Hello</p> becomes elem.Paragraph(vecty.Text(i18n.T("Hello"))).
+type Translation struct {
+	// Func is the translation function, ex. i18n.T, it must take the message ID and return the translated text.
+	Func string `json:"func"`
+	// Import is the import path of the package containing Func, ex. example.com/app/i18n.
+	Import string `json:"import"`
+}
+
+// Message is a piece of text to translate and the places it's used.
+type Message struct {
+	ID   string       `json:"id"`
+	Refs []MessageRef `json:"refs"`
+}
+
+// MessageRef is a place a message is used.
+type MessageRef struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (r MessageRef) String() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// Catalog is the set of messages in a group of templates, in the order they are first used.
+type Catalog struct {
+	Messages []*Message
+	byID     map[string]*Message
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{byID: map[string]*Message{}}
+}
+
+func (c *Catalog) add(id string, ref MessageRef) {
+	m, ok := c.byID[id]
+	if !ok {
+		m = &Message{ID: id}
+		c.byID[id] = m
+		c.Messages = append(c.Messages, m)
+	}
+	m.Refs = append(m.Refs, ref)
+}
+
+// Extract adds the text of the templates in the source to the catalog.
+func (c *Catalog) Extract(filename string, src []byte) error {
+	tags, err := ExtractHtml(filename, io.Discard, src)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := c.extractTag(filename, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) extractTag(filename string, tag *html.TagOrText) error {
+	ref := MessageRef{File: filename, Line: tag.Line}
+	if tag.IsComment {
+		return nil
+	}
+	if tag.TagName == "t" {
+		msg, err := translateElementMessage(tag)
+		if err != nil {
+			return &PositionError{Filename: filename, Line: tag.Line, Err: err}
+		}
+		c.add(msg, ref)
+		return nil
+	}
+	if tag.TagName == "" {
+		parts, err := tokenizeExpressionParts(tag.Text)
+		if err != nil {
+			return &PositionError{Filename: filename, Line: tag.Line, Err: err}
+		}
+		for _, part := range parts {
+			if !part.isEmbeddedCode && isTranslatableText(part.value) {
+				c.add(part.value, ref)
+			}
+		}
+	}
+	nested, err := nestedTemplates(tag)
+	if err != nil {
+		return &PositionError{Filename: filename, Line: tag.Line, Err: err}
+	}
+	for _, child := range nested {
+		if err := c.extractTag(filename, child); err != nil {
+			return err
+		}
+	}
+	if tag.TagName == "" {
+		return nil
+	}
+	for _, attr := range tag.Attr {
+		if translatableAttributes[attr.Name] && isStaticValue(attr.Value) && isTranslatableText(attr.Value) {
+			c.add(attr.Value, ref)
+		}
+	}
+	for _, child := range tag.Children {
+		if err := c.extractTag(filename, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Escapes a string for a .pot file, unlike Go strings other characters, ex. UTF-8, are written as is.
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// WritePOT writes the catalog as a gettext .pot file.
+func (c *Catalog) WritePOT(w io.Writer) error {
+	b := bytes.NewBufferString("msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	for _, m := range c.Messages {
+		b.WriteString("\n")
+		for _, ref := range m.Refs {
+			fmt.Fprintf(b, "#: %s\n", ref)
+		}
+		fmt.Fprintf(b, "msgid \"%s\"\nmsgstr \"\"\n", poEscaper.Replace(m.ID))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteJSON writes the catalog as a JSON array of messages.
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	messages := c.Messages
+	if messages == nil {
+		messages = []*Message{}
+	}
+	b, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Only text containing letters is translated, ex. "|" or "-" are left as is.
+func isTranslatableText(s string) bool {
+	for _, c := range s {
+		if unicode.IsLetter(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func isStaticValue(value string) bool {
+	parts, err := tokenizeExpressionParts(value)
+	return err == nil && len(parts) == 1 && !parts[0].isEmbeddedCode
+}
+
+// Gets the expression for the value of an attribute, which is translated when the attribute is shown to the user and
+// its value is static, ex. placeholder="Your name", the same as the attributes extracted into the catalog.
+func (ctx *tagContext) attributeValueExpr(attr *html.Attr, value dst.Expr) dst.Expr {
+	if translatableAttributes[attr.Name] && isStaticValue(attr.Value) {
+		return ctx.textExpr(attr.Value)
+	}
+	return value
+}
+
+// Gets the expression for a piece of static text, which is a call to the translation function when translating.
+func (ctx *tagContext) textExpr(s string) dst.Expr {
+	if ctx.translation == nil || !isTranslatableText(s) {
+		return stringLit(s)
+	}
+	if ctx.translation.Import != "" {
+		ctx.requireImport(ctx.translation.Import)
+	}
+	return &dst.CallExpr{Fun: dst.NewIdent(ctx.translation.Func), Args: []dst.Expr{stringLit(s)}}
+}
+
+var (
+	icuArgumentNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	icuOtherFormRegex    = regexp.MustCompile(`(^|\s)other\s*$`)
+	whitespaceRegex      = regexp.MustCompile(`\s+`)
+)
+
+// Gets the ICU message of a <t> element, ex. <t n={c.count}>You have {n, plural, one {# message} other {# messages}}</t>
+// The whitespace of the message is collapsed so the formatting of the template doesn't change the message.
+func translateElementMessage(tag *html.TagOrText) (string, error) {
+	var parts []string
+	for _, child := range tag.Children {
+		if child.IsComment {
+			continue
+		}
+		if child.TagName != "" {
+			return "", fmt.Errorf("the <t> element can only contain text, but contained <%s>", child.TagName)
+		}
+		parts = append(parts, child.Text)
+	}
+	msg := strings.TrimSpace(whitespaceRegex.ReplaceAllString(strings.Join(parts, " "), " "))
+	if msg == "" {
+		return "", fmt.Errorf("the <t> element must contain a message")
+	}
+	return msg, nil
+}
+
+// Converts a <t> element into a single call to the translation function with the message and its arguments, ex.
+// <t name={c.name}>Hello {name}</t> becomes vecty.Text(i18n.T("Hello {name}", "name", c.name)).
+func parseTranslateElement(ctx *tagContext, tag *html.TagOrText) (dst.Expr, error) {
+	if ctx.translation == nil {
+		return nil, fmt.Errorf("the <t> element requires a translation function, see translate in the configuration")
+	}
+	msg, err := translateElementMessage(tag)
+	if err != nil {
+		return nil, err
+	}
+	used, err := icuArguments(msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message '%s': %w", msg, err)
+	}
+	args := []dst.Expr{stringLit(msg)}
+	defined := map[string]bool{}
+	for _, attr := range tag.Attr {
+		name := attr.RawName
+		if name == "" {
+			name = attr.Name
+		}
+		if !icuArgumentNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid argument name '%s' in <t> element", name)
+		}
+		if !containsString(used, name) {
+			return nil, fmt.Errorf("argument '%s' is not used in message '%s'", name, msg)
+		}
+		defined[name] = true
+		args, err = parseSingleAttributeValue(ctx, append(args, stringLit(name)), attr.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range used {
+		if !defined[name] {
+			return nil, fmt.Errorf("missing argument '%s' for message '%s', add it as an attribute, ex. <t %s={...}>", name, msg, name)
+		}
+	}
+	if ctx.translation.Import != "" {
+		ctx.requireImport(ctx.translation.Import)
+	}
+	call := &dst.CallExpr{Fun: dst.NewIdent(ctx.translation.Func), Args: args}
+	return simpleCallExpr("vecty", "Text", []dst.Expr{call}), nil
+}
+
+// Gets the names of the arguments used in an ICU message, in the order they are first used, ex. name and n in
+// "Hello {name}, you have {n, plural, one {# message} other {# messages}}".
+func icuArguments(msg string) ([]string, error) {
+	var names []string
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '\'':
+			i = skipICUQuote(msg, i)
+		case '}':
+			return nil, fmt.Errorf("unexpected '}'")
+		case '{':
+			end, err := matchingBrace(msg, i)
+			if err != nil {
+				return nil, err
+			}
+			argNames, err := icuArgument(msg[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range argNames {
+				if !containsString(names, name) {
+					names = append(names, name)
+				}
+			}
+			i = end
+		}
+	}
+	return names, nil
+}
+
+// Parses an argument, ex. "name" or "n, plural, one {# message} other {# messages}", returning its name and the names
+// of any arguments used in its forms.
+func icuArgument(arg string) ([]string, error) {
+	parts := strings.SplitN(arg, ",", 3)
+	name := strings.TrimSpace(parts[0])
+	if !icuArgumentNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid argument '{%s}'", arg)
+	}
+	names := []string{name}
+	if len(parts) < 3 {
+		return names, nil
+	}
+	switch argType := strings.TrimSpace(parts[1]); argType {
+	case "plural", "select", "selectordinal":
+		forms := parts[2]
+		hasOther := false
+		for i := 0; i < len(forms); i++ {
+			if forms[i] != '{' {
+				continue
+			}
+			if icuOtherFormRegex.MatchString(forms[:i]) {
+				hasOther = true
+			}
+			end, err := matchingBrace(forms, i)
+			if err != nil {
+				return nil, err
+			}
+			formNames, err := icuArguments(forms[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, formNames...)
+			forms = forms[end+1:]
+			i = -1
+		}
+		if !hasOther {
+			return nil, fmt.Errorf("the %s argument '%s' is missing the 'other' form", argType, name)
+		}
+	}
+	return names, nil
+}
+
+// Gets the index of the brace closing the brace at start, braces quoted with apostrophes are skipped.
+func matchingBrace(s string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipICUQuote(s, i)
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("missing closing '}'")
+}
+
+// Skips the ICU apostrophe quoting starting at the apostrophe at i, ex. two apostrophes are an apostrophe and '{' is a
+// literal brace. An apostrophe only starts quoted text when followed by a syntax character, otherwise it's an
+// apostrophe, ex. it's.
+// Returns the index of the last character that is quoted, which is i when the apostrophe is not quoting anything.
+func skipICUQuote(s string, i int) int {
+	if i+1 >= len(s) {
+		return i
+	}
+	switch s[i+1] {
+	case '\'':
+		return i + 1
+	case '{', '}', '#', '|':
+		// The quoted text ends at the next single apostrophe, or the end of the message if there is none.
+		for j := i + 1; j < len(s); j++ {
+			if s[j] != '\'' {
+				continue
+			}
+			if j+1 < len(s) && s[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j
+		}
+		return len(s) - 1
+	}
+	return i
+}
@@ -0,0 +1,210 @@
+package tvecty
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const i18nSrc = `package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div title="Greeting">
+		<p>Hello {c.name}!</p>
+		<input placeholder="Your name" value={c.name} alt={c.alt} />
+		<span> | </span>
+		<p>Hello</p>
+	</div>
+}
+`
+
+func TestCatalog_ExtractsTextAndTranslatableAttributes(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Extract("comps/comp.vtpl", []byte(i18nSrc)))
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, c.WritePOT(out))
+	requireEqStr(t, out.String(), `
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#: comps/comp.vtpl:4
+msgid "Greeting"
+msgstr ""
+
+#: comps/comp.vtpl:5
+#: comps/comp.vtpl:8
+msgid "Hello"
+msgstr ""
+
+#: comps/comp.vtpl:6
+msgid "Your name"
+msgstr ""
+`)
+}
+
+func TestCatalog_EscapesPOStrings(t *testing.T) {
+	c := &Catalog{Messages: []*Message{{ID: "Say \"café\"\ta\\b\nagain"}}}
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, c.WritePOT(out))
+	require.Contains(t, out.String(), `msgid "Say \"café\"\ta\\b\nagain"`)
+}
+
+func TestCatalog_WritesJSON(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Extract("comp.vtpl", []byte(`package comps
+
+var x = <p title="Say &#34;hi&#34;">Hi</p>
+`)))
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, c.WriteJSON(out))
+	requireEqStr(t, out.String(), `
+[
+  {
+    "id": "Say \"hi\"",
+    "refs": [
+      {
+        "file": "comp.vtpl",
+        "line": 3
+      }
+    ]
+  },
+  {
+    "id": "Hi",
+    "refs": [
+      {
+        "file": "comp.vtpl",
+        "line": 3
+      }
+    ]
+  }
+]`)
+}
+
+func TestCompiler_TranslatesStaticText(t *testing.T) {
+	c := NewCompiler()
+	c.Translation = &Translation{Func: "i18n.T", Import: "example.com/app/i18n"}
+	out, err := compileRender(t, c, `<div title="Greeting" id="main">
+		<p>Hello {c.name}!</p>
+		<span> | </span>
+	</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import "example.com/app/i18n"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Attribute("title", i18n.T("Greeting")),
+			vecty.Attribute("id", "main"),
+		),
+		elem.Paragraph(
+			vecty.Text(i18n.T("Hello")),
+			c.name,
+			vecty.Text("!"),
+		),
+		elem.Span(
+			vecty.Text("|"),
+		),
+	)
+}`)
+}
+
+func translatingCompiler() *Compiler {
+	c := NewCompiler()
+	c.Translation = &Translation{Func: "i18n.T", Import: "example.com/app/i18n"}
+	return c
+}
+
+func TestCompiler_TranslatesTheAttributesOfCustomElements(t *testing.T) {
+	c := translatingCompiler()
+	require.NoError(t, c.RegisterElement(CustomElement{
+		Tag:        "sl-input",
+		Properties: []string{"placeholder"},
+		Attributes: []string{"title"},
+	}))
+	out, err := compileRender(t, c, `<sl-input placeholder="Your name" title="Name" />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import "example.com/app/i18n"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("sl-input",
+		vecty.Markup(
+			vecty.Property("placeholder", i18n.T("Your name")),
+			vecty.Attribute("title", i18n.T("Name")),
+		),
+	)
+}`)
+}
+
+func TestCompiler_TranslatesMessagesWithArguments(t *testing.T) {
+	out, err := compileRender(t, translatingCompiler(), `<p>
+		<t userName={c.name} n={c.unread}>
+			Hi {userName}, you have
+			{n, plural, one {# new message} other {# new messages}}
+		</t>
+	</p>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import "example.com/app/i18n"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Paragraph(
+		vecty.Text(i18n.T("Hi {userName}, you have {n, plural, one {# new message} other {# new messages}}", "userName", c.name, "n", c.unread)),
+	)
+}`)
+}
+
+func TestCompiler_ValidatesTranslatedMessages(t *testing.T) {
+	cases := []struct {
+		html string
+		err  string
+	}{
+		{`<t>Hello {name}</t>`, "missing argument 'name' for message 'Hello {name}', add it as an attribute, ex. <t name={...}>"},
+		{`<t name={c.name} n={c.n}>Hello {name}</t>`, "argument 'n' is not used in message 'Hello {name}'"},
+		{`<t n={c.n}>{n, plural, one {# item}}</t>`, "invalid message '{n, plural, one {# item}}': the plural argument 'n' is missing the 'other' form"},
+		{`<t n={c.n}>{n, select, a {{from}} other {x}}</t>`, "missing argument 'from' for message '{n, select, a {{from}} other {x}}', add it as an attribute, ex. <t from={...}>"},
+		{`<t n={c.n}>Items: {n</t>`, "invalid message 'Items: {n': missing closing '}'"},
+		{`<t><b>Hi</b></t>`, "the <t> element can only contain text, but contained <b>"},
+	}
+	for _, tc := range cases {
+		_, err := compileRender(t, translatingCompiler(), tc.html)
+		require.EqualError(t, err, "comp.vtpl:4: "+tc.err)
+	}
+}
+
+func TestCompiler_SkipsQuotedTextInTranslatedMessages(t *testing.T) {
+	out, err := compileRender(t, translatingCompiler(), `<t n={c.n}>It''s '{'{n}'}'</t>`)
+	require.NoError(t, err)
+	require.Contains(t, out, `i18n.T("It''s '{'{n}'}'", "n", c.n)`)
+
+	args, err := icuArguments("{n, plural, one {'{'# item'}'} other {'{n}' is {n}}}, it's '{'{name}")
+	require.NoError(t, err)
+	require.Equal(t, []string{"n", "name"}, args)
+}
+
+func TestCompiler_TranslateElementRequiresATranslationFunction(t *testing.T) {
+	_, err := compileRender(t, NewCompiler(), `<t>Hello</t>`)
+	require.EqualError(t, err, "comp.vtpl:4: the <t> element requires a translation function, see translate in the configuration")
+}
+
+func TestCatalog_ExtractsTranslateElementsAsOneMessage(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Extract("comp.vtpl", []byte(`package comps
+
+var x = <p>
+	<t n={c.n}>You have
+		{n, plural, one {# message} other {# messages}}</t>
+</p>
+`)))
+	require.Len(t, c.Messages, 1)
+	require.Equal(t, "You have {n, plural, one {# message} other {# messages}}", c.Messages[0].ID)
+	require.Equal(t, []MessageRef{{File: "comp.vtpl", Line: 4}}, c.Messages[0].Refs)
+}
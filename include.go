@@ -0,0 +1,247 @@
+package tvecty
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The fills of the <layout> currently being converted, by slot name. Content of the <layout> that is not in a <fill>
+// fills the unnamed slot.
+type layoutSlots struct {
+	// The names of the fills in the order they are in the page.
+	names []string
+	fills map[string][]dst.Expr
+	used  map[string]bool
+}
+
+// IsPartial checks if a template file is a partial, ex. a file only used by <include> or <layout>. Partials contain a
+// template rather than Go source, so they are not compiled on their own.
+func IsPartial(src []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(src), []byte("<"))
+}
+
+// Loads the template included by a tag, ex. <include src="partials/header.vtpl" />. The src is relative to the file
+// including it. Returns the path of the template and its root element.
+func loadTemplate(ctx *tagContext, tag *html.TagOrText) (string, *html.TagOrText, error) {
+	var src string
+	for _, attr := range tag.Attr {
+		if attr.Name == "src" {
+			src = attr.Value
+		}
+	}
+	if src == "" || strings.Contains(src, "{") {
+		return "", nil, fmt.Errorf("the <%s> element must have a src path, ex. <%s src=\"partials/header.vtpl\">", tag.TagName, tag.TagName)
+	}
+	path := filepath.Join(filepath.Dir(ctx.filename), src)
+	chain := append(append([]string{}, ctx.includes...), ctx.filename)
+	for _, included := range chain {
+		if included == path {
+			return "", nil, fmt.Errorf("include cycle: %s", strings.Join(append(chain, path), " -> "))
+		}
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("included file '%s' does not exist", path)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read included file '%s': %w", path, err)
+	}
+	trimmed := bytes.TrimLeft(b, " \t\r\n")
+	r := bytes.NewReader(trimmed)
+	root, _, err := html.ParseHtml(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot parse included file '%s': %w", path, err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if root == nil || len(bytes.TrimSpace(rest)) > 0 {
+		return "", nil, fmt.Errorf("included file '%s' must contain a single root element", path)
+	}
+	// Lines are relative to the start of the file rather than the root element.
+	offsetLines(root, bytes.Count(b[:len(b)-len(trimmed)], []byte("\n")))
+	if len(appendScopedStyles(nil, root)) > 0 {
+		return "", nil, fmt.Errorf("included file '%s' contains scoped styles, which are only supported in the including file", path)
+	}
+	return path, root, nil
+}
+
+// Converts the root of an included template in the scope of its own file. Errors within the template are positioned in
+// the template and followed by the chain of files that included it.
+func includedTemplateToAst(ctx *tagContext, tag *html.TagOrText, path string, root *html.TagOrText) (dst.Expr, error) {
+	filename, line := ctx.filename, tag.Line
+	ctx.includes = append(ctx.includes, filename)
+	ctx.filename = path
+	exprs, err := tagToAst(ctx, nil, root)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n\tincluded from %s:%d", err, filename, line)
+	}
+	ctx.includes = ctx.includes[:len(ctx.includes)-1]
+	ctx.filename = filename
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("included file '%s' does not render anything", path)
+	}
+	return exprs[0], nil
+}
+
+// The attributes of an <include> or <layout> other than src, which are passed to the template as variables.
+func templateParams(tag *html.TagOrText) []*html.Attr {
+	var params []*html.Attr
+	for _, attr := range tag.Attr {
+		if attr.Name != "src" {
+			params = append(params, attr)
+		}
+	}
+	return params
+}
+
+// Converts an <include> element, ex. <include src="partials/header.vtpl" title={c.title} />, by inlining the template
+// of the file. The other attributes are variables in the template, ex. {s:title}.
+func parseIncludeElement(ctx *tagContext, tag *html.TagOrText) (dst.Expr, error) {
+	if hasContent(tag.Children) {
+		return nil, fmt.Errorf("the <include> element cannot have content, use a <layout> to pass content to a template")
+	}
+	path, root, err := loadTemplate(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	params, err := letBindings(ctx, templateParams(tag), "")
+	if err != nil {
+		return nil, err
+	}
+	// The slots of a layout including the template are not available to it.
+	slots := ctx.slots
+	ctx.slots = nil
+	expr, err := includedTemplateToAst(ctx, tag, path, root)
+	if err != nil {
+		return nil, err
+	}
+	ctx.slots = slots
+	if len(params) == 0 {
+		return expr, nil
+	}
+	return letFunc(params, expr), nil
+}
+
+// Converts a <layout> element, ex. <layout src="base.vtpl"><fill name="title">Home</fill><p>Content</p></layout>, by
+// inlining the template of the file with its <slot> elements replaced by the content of the matching <fill>. Content
+// outside of a <fill> replaces the unnamed <slot />. Like <include> the other attributes are variables in the template.
+func parseLayoutElement(ctx *tagContext, tag *html.TagOrText) (dst.Expr, error) {
+	path, root, err := loadTemplate(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	params, err := letBindings(ctx, templateParams(tag), "")
+	if err != nil {
+		return nil, err
+	}
+	// The fills are converted in the scope of the page, so errors are positioned in the page.
+	slots := &layoutSlots{fills: map[string][]dst.Expr{}, used: map[string]bool{}}
+	var content []*html.TagOrText
+	for _, child := range tag.Children {
+		if child.TagName != "fill" {
+			content = append(content, child)
+			continue
+		}
+		ctx.line = child.Line
+		name := ""
+		for _, attr := range child.Attr {
+			if attr.Name == "name" {
+				name = attr.Value
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("the <fill> element must have a name, ex. <fill name=\"title\">")
+		}
+		if _, ok := slots.fills[name]; ok {
+			return nil, fmt.Errorf("the slot '%s' is filled more than once", name)
+		}
+		if slots.fills[name], err = tagsToAst(ctx, nil, child.Children); err != nil {
+			return nil, err
+		}
+		slots.names = append(slots.names, name)
+	}
+	if slots.fills[""], err = tagsToAst(ctx, nil, content); err != nil {
+		return nil, err
+	}
+	slots.names = append(slots.names, "")
+	// The parameters are declared around the whole layout, so they would hide the variables of the page used in the
+	// fills.
+	for _, attr := range templateParams(tag) {
+		for _, name := range slots.names {
+			if usesIdent(slots.fills[name], attr.RawName) {
+				ctx.line = tag.Line
+				return nil, fmt.Errorf("the layout variable '%s' would hide the variable of the same name used in the content of the <layout>, rename the layout variable", attr.RawName)
+			}
+		}
+	}
+
+	parentSlots := ctx.slots
+	ctx.slots = slots
+	expr, err := includedTemplateToAst(ctx, tag, path, root)
+	if err != nil {
+		return nil, err
+	}
+	ctx.slots = parentSlots
+	ctx.line = tag.Line
+	for _, name := range slots.names {
+		if len(slots.fills[name]) > 0 && !slots.used[name] {
+			if name == "" {
+				return nil, fmt.Errorf("the layout '%s' has no unnamed <slot /> for the content outside of a <fill>", path)
+			}
+			return nil, fmt.Errorf("the layout '%s' has no slot named '%s'", path, name)
+		}
+	}
+	if len(params) == 0 {
+		return expr, nil
+	}
+	return letFunc(params, expr), nil
+}
+
+// Checks if the identifier name is used in exprs, not counting the names selected from a value or package, ex. title
+// in c.title.
+func usesIdent(exprs []dst.Expr, name string) bool {
+	found := false
+	var visit func(n dst.Node) bool
+	visit = func(n dst.Node) bool {
+		switch n := n.(type) {
+		case *dst.Ident:
+			found = found || n.Name == name
+		case *dst.SelectorExpr:
+			dst.Inspect(n.X, visit)
+			return false
+		}
+		return !found
+	}
+	for _, expr := range exprs {
+		dst.Inspect(expr, visit)
+	}
+	return found
+}
+
+// Converts a <slot> element of a layout, ex. <slot name="title">Default title</slot>, into the content of the
+// matching <fill>, or the content of the slot if the slot is not filled.
+func slotToAst(ctx *tagContext, existing []dst.Expr, tag *html.TagOrText) ([]dst.Expr, error) {
+	name := ""
+	for _, attr := range tag.Attr {
+		if attr.Name == "name" {
+			name = attr.Value
+		}
+	}
+	if ctx.slots.used[name] {
+		return nil, fmt.Errorf("the slot '%s' is used more than once", name)
+	}
+	ctx.slots.used[name] = true
+	fill := ctx.slots.fills[name]
+	if len(fill) == 0 {
+		return tagsToAst(ctx, existing, tag.Children)
+	}
+	return append(existing, fill...), nil
+}
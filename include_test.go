@@ -0,0 +1,178 @@
+package tvecty
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Compiles html in a file next to the templates in files, errors are relative to the directory of the files.
+func compileWithTemplates(t *testing.T, files map[string]string, html string) (string, error) {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	}
+	in := `package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return ` + html + `
+}
+`
+	out := bytes.NewBuffer(nil)
+	err := NewCompiler().ConvertToVecty(filepath.Join(dir, "comp.vtpl"), out, []byte(in))
+	if err != nil {
+		return "", errors.New(strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), ""))
+	}
+	return out.String(), nil
+}
+
+var testTemplates = map[string]string{
+	"partials/header.vtpl": "\n<header>\n\t<h1>{s:title}</h1>\n\t<include src=\"logo.vtpl\" />\n</header>\n",
+	"partials/logo.vtpl":   `<img src="/logo.png" />`,
+	"base.vtpl": `<div class="page">
+	<nav><slot name="nav">Home</slot></nav>
+	<main><slot /></main>
+	<footer><slot name="footer" /></footer>
+</div>`,
+	"cycle/a.vtpl":   `<div><include src="b.vtpl" /></div>`,
+	"cycle/b.vtpl":   `<p><include src="a.vtpl" /></p>`,
+	"missing.vtpl":   "<div>\n<include src=\"nope.vtpl\" />\n</div>",
+	"invalid.vtpl":   "<div>\n<p>{q:x}</p>\n</div>",
+	"two-roots.vtpl": "<p>a</p><p>b</p>",
+}
+
+func TestCompiler_InlinesIncludedTemplates(t *testing.T) {
+	out, err := compileWithTemplates(t, testTemplates, `<include src="partials/header.vtpl" title={c.title} />`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() *vecty.HTML {
+		title := c.title
+		_ = title
+		return elem.Header(
+			elem.Heading1(
+				vecty.Text(title),
+			),
+			elem.Image(
+				vecty.Markup(
+					vecty.Attribute("src", "/logo.png"),
+				),
+			),
+		)
+	}()
+}`)
+}
+
+func TestCompiler_FillsTheSlotsOfLayouts(t *testing.T) {
+	out, err := compileWithTemplates(t, testTemplates,
+		`<layout src="base.vtpl"><fill name="footer"><p>Foot</p></fill><p>Body</p><p>More</p></layout>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("page"),
+		),
+		elem.Navigation(
+			vecty.Text("Home"),
+		),
+		elem.Main(
+			elem.Paragraph(
+				vecty.Text("Body"),
+			),
+			elem.Paragraph(
+				vecty.Text("More"),
+			),
+		),
+		elem.Footer(
+			elem.Paragraph(
+				vecty.Text("Foot"),
+			),
+		),
+	)
+}`)
+}
+
+func TestCompiler_ReportsIncludeErrorsWithTheIncludeChain(t *testing.T) {
+	cases := []struct {
+		html string
+		err  string
+	}{
+		{`<div><include src="cycle/a.vtpl" /></div>`, `cycle/b.vtpl:1: include cycle: comp.vtpl -> cycle/a.vtpl -> cycle/b.vtpl -> cycle/a.vtpl
+	included from cycle/a.vtpl:1
+	included from comp.vtpl:4`},
+		{`<div><include src="missing.vtpl" /></div>`, `missing.vtpl:2: included file 'nope.vtpl' does not exist
+	included from comp.vtpl:4`},
+		{`<div><include src="invalid.vtpl" /></div>`, `invalid.vtpl:2: unknown modifier 'q' in expression: 'q:x'
+	included from comp.vtpl:4`},
+		{`<include src="two-roots.vtpl" />`, `comp.vtpl:4: included file 'two-roots.vtpl' must contain a single root element`},
+		{`<include src={c.path} />`, `comp.vtpl:4: the <include> element must have a src path, ex. <include src="partials/header.vtpl">`},
+		{`<layout src="base.vtpl"><fill name="side"><p>Side</p></fill></layout>`, `comp.vtpl:4: the layout 'base.vtpl' has no slot named 'side'`},
+		{`<layout src="partials/logo.vtpl"><p>Body</p></layout>`, `comp.vtpl:4: the layout 'partials/logo.vtpl' has no unnamed <slot /> for the content outside of a <fill>`},
+		{`<div><fill name="x"></fill></div>`, `comp.vtpl:4: the <fill> element must be a child of a <layout>`},
+		{"<layout src=\"base.vtpl\" title={c.title}>\n<fill name=\"nav\"><p>{s:title}</p></fill>\n</layout>",
+			`comp.vtpl:4: the layout variable 'title' would hide the variable of the same name used in the content of the <layout>, rename the layout variable`},
+	}
+	for _, tc := range cases {
+		_, err := compileWithTemplates(t, testTemplates, tc.html)
+		require.EqualError(t, err, tc.err)
+	}
+}
+
+func TestIsPartial(t *testing.T) {
+	require.True(t, IsPartial([]byte("\n<header></header>\n")))
+	require.False(t, IsPartial([]byte("package comps\n")))
+}
+
+func TestCompiler_IncludesMayContainComments(t *testing.T) {
+	out, err := compileWithTemplates(t, testTemplates, `<include src="partials/logo.vtpl"><!-- the logo --></include>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Image(
+		vecty.Markup(
+			vecty.Attribute("src", "/logo.png"),
+		),
+	)
+}`)
+}
+
+func TestCompiler_LayoutVariablesMayShareNamesWithSelectedFields(t *testing.T) {
+	out, err := compileWithTemplates(t, testTemplates, `<layout src="base.vtpl" title={c.title}><p>{s:c.title}</p></layout>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() *vecty.HTML {
+		title := c.title
+		_ = title
+		return elem.Div(
+			vecty.Markup(
+				vecty.Class("page"),
+			),
+			elem.Navigation(
+				vecty.Text("Home"),
+			),
+			elem.Main(
+				elem.Paragraph(
+					vecty.Text(c.title),
+				),
+			),
+			elem.Footer(),
+		)
+	}()
+}`)
+}
@@ -0,0 +1,158 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"strings"
+)
+
+const letAttributePrefix = "let:"
+
+// Declares the variables of a <let> element or of let: attributes, ex. <let name={c.user.Name}> or
+// <p let:name={c.user.Name}>, as statements. Each variable is also assigned to _ so unused variables are not an error.
+func letBindings(ctx *tagContext, attrs []*html.Attr, prefix string) ([]dst.Stmt, error) {
+	var stmts []dst.Stmt
+	for _, attr := range attrs {
+		if !strings.HasPrefix(attr.Name, prefix) {
+			continue
+		}
+		// Variable names are case sensitive, ex. displayName.
+		name := attr.RawName[len(prefix):]
+		if !token.IsIdentifier(name) || name == "_" {
+			return nil, fmt.Errorf("invalid let variable name '%s', must be a Go identifier", name)
+		}
+		parts, err := tokenizeExpressionParts(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 1 {
+			return nil, fmt.Errorf("let variable '%s' must be a single expression or a string, but was '%s'", name, attr.Value)
+		}
+		value, err := parseExpressionOrText(ctx, parts[0].value, parts[0].isEmbeddedCode, false, false)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts,
+			&dst.AssignStmt{Lhs: []dst.Expr{dst.NewIdent(name)}, Tok: token.DEFINE, Rhs: []dst.Expr{value}},
+			&dst.AssignStmt{Lhs: []dst.Expr{dst.NewIdent("_")}, Tok: token.ASSIGN, Rhs: []dst.Expr{dst.NewIdent(name)}},
+		)
+	}
+	return stmts, nil
+}
+
+// Checks if the element declares variables with let: attributes.
+func hasLetAttributes(tag *html.TagOrText) bool {
+	for _, attr := range tag.Attr {
+		if strings.HasPrefix(attr.Name, letAttributePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a <let> element, ex. <let name={c.user.Name}><p>{s:name}</p></let>, the variables are only in scope for the
+// content of the element.
+func parseLetElement(ctx *tagContext, tag *html.TagOrText) (dst.Expr, error) {
+	stmts, err := letBindings(ctx, tag.Attr, "")
+	if err != nil {
+		return nil, err
+	}
+	if !hasContent(tag.Children) {
+		return nil, fmt.Errorf("the <let> element must have content")
+	}
+	children, err := tagsToAst(ctx, nil, tag.Children)
+	if err != nil {
+		return nil, err
+	}
+	for i, child := range children {
+		children[i] = conditionalChild(child)
+	}
+	switch len(children) {
+	case 0:
+		ctx.line = tag.Line
+		return nil, fmt.Errorf("the <let> element must have content")
+	case 1:
+		return letFunc(stmts, children[0]), nil
+	}
+	list := &dst.CompositeLit{Type: &dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("List")}, Elts: children}
+	for _, child := range children {
+		child.Decorations().Before = dst.NewLine
+		child.Decorations().After = dst.NewLine
+	}
+	return letFunc(stmts, list), nil
+}
+
+// Converts conditional content, ex. vecty.If(c.ok, elem.Bold()) from {c.ok && <b/>}, into a function returning the
+// content or nil. vecty.If returns a vecty.MarkupOrChild, which is not a vecty.ComponentOrHTML, so it cannot be the
+// result of a let function or be in a vecty.List.
+func conditionalChild(expr dst.Expr) dst.Expr {
+	if !isVectyCall(expr, "If") || len(expr.(*dst.CallExpr).Args) != 2 {
+		return expr
+	}
+	cond, content := expr.(*dst.CallExpr).Args[0], expr.(*dst.CallExpr).Args[1]
+	content.Decorations().Before = dst.None
+	content.Decorations().After = dst.None
+	body := []dst.Stmt{
+		&dst.IfStmt{Cond: cond, Body: &dst.BlockStmt{List: []dst.Stmt{&dst.ReturnStmt{Results: []dst.Expr{content}}}}},
+		&dst.ReturnStmt{Results: []dst.Expr{dst.NewIdent("nil")}},
+	}
+	return calledFunc(&dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("ComponentOrHTML")}, body)
+}
+
+// Wraps result in a function that is called immediately, so the variables are computed once and only in scope for the
+// result, ex. func() vecty.ComponentOrHTML { name := c.user.Name; _ = name; return result }(). When the result is an
+// element the function returns a *vecty.HTML, so it can be used where an element is required, ex. as the result of
+// RenderBody.
+func letFunc(stmts []dst.Stmt, result dst.Expr) dst.Expr {
+	result.Decorations().Before = dst.None
+	result.Decorations().After = dst.None
+	var resultType dst.Expr = &dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("ComponentOrHTML")}
+	if isHTMLExpr(result) {
+		resultType = &dst.StarExpr{X: &dst.SelectorExpr{X: dst.NewIdent("vecty"), Sel: dst.NewIdent("HTML")}}
+	}
+	return calledFunc(resultType, append(stmts, &dst.ReturnStmt{Results: []dst.Expr{result}}))
+}
+
+// A function literal returning resultType that is called immediately, ex. func() vecty.ComponentOrHTML { ... }().
+func calledFunc(resultType dst.Expr, body []dst.Stmt) dst.Expr {
+	return &dst.CallExpr{
+		Fun: &dst.FuncLit{
+			Type: &dst.FuncType{
+				Func:    true,
+				Params:  &dst.FieldList{},
+				Results: &dst.FieldList{List: []*dst.Field{{Type: resultType}}},
+			},
+			Body: &dst.BlockStmt{List: body},
+		},
+	}
+}
+
+// Checks if expr is known to be a *vecty.HTML, ex. elem.Div(), vecty.Tag("my-element") or a let function returning
+// an element.
+func isHTMLExpr(expr dst.Expr) bool {
+	call, ok := expr.(*dst.CallExpr)
+	if !ok {
+		return false
+	}
+	switch fun := call.Fun.(type) {
+	case *dst.SelectorExpr:
+		pkg, ok := fun.X.(*dst.Ident)
+		if !ok {
+			return false
+		}
+		return pkg.Name == "elem" || (pkg.Name == "vecty" && (fun.Sel.Name == "Tag" || fun.Sel.Name == "Text"))
+	case *dst.FuncLit:
+		if fun.Type.Results == nil || len(fun.Type.Results.List) != 1 {
+			return false
+		}
+		star, ok := fun.Type.Results.List[0].Type.(*dst.StarExpr)
+		if !ok {
+			return false
+		}
+		sel, ok := star.X.(*dst.SelectorExpr)
+		return ok && sel.Sel.Name == "HTML"
+	}
+	return false
+}
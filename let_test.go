@@ -0,0 +1,122 @@
+package tvecty
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_ConvertsLetElements(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(),
+		`<let displayName={c.user.Profile.DisplayName}><p title={displayName}>{s:displayName}</p></let>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() *vecty.HTML {
+		displayName := c.user.Profile.DisplayName
+		_ = displayName
+		return elem.Paragraph(
+			vecty.Markup(
+				vecty.Attribute("title", displayName),
+			),
+			vecty.Text(displayName),
+		)
+	}()
+}`)
+}
+
+func TestCompiler_ReturnsAListFromLetElementsWithMultipleChildren(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div><let name={c.name} n={d:c.n}><p>{s:name}</p><p>{s:n}</p></let></div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import "strconv"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(func() vecty.ComponentOrHTML {
+		name := c.name
+		_ = name
+		n := strconv.FormatInt(int64(c.n), 10)
+		_ = n
+		return vecty.List{
+			elem.Paragraph(
+				vecty.Text(name),
+			),
+			elem.Paragraph(
+				vecty.Text(n),
+			),
+		}
+	}())
+}`)
+}
+
+func TestCompiler_ConvertsLetAttributes(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div let:userName={c.user.Name} class="x"><p>{s:userName}</p></div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() *vecty.HTML {
+		userName := c.user.Name
+		_ = userName
+		return elem.Div(
+			vecty.Markup(
+				vecty.Class("x"),
+			),
+			elem.Paragraph(
+				vecty.Text(userName),
+			),
+		)
+	}()
+}`)
+}
+
+func TestCompiler_ErrorsOnInvalidLetVariables(t *testing.T) {
+	cases := []struct {
+		html string
+		err  string
+	}{
+		{`<let 1x={c.x}><p/></let>`, "invalid let variable name '1x', must be a Go identifier"},
+		{`<p let:_={c.x}></p>`, "invalid let variable name '_', must be a Go identifier"},
+		{`<let x="{c.a}{c.b}"><p/></let>`, "let variable 'x' must be a single expression or a string, but was '{c.a}{c.b}'"},
+		{`<let x={c.x}></let>`, "the <let> element must have content"},
+		{`<let x={c.x}><!-- c --></let>`, "the <let> element must have content"},
+		{"<let x={c.x}>\n\t<!-- c -->\n</let>", "the <let> element must have content"},
+	}
+	for _, tc := range cases {
+		_, err := compileRender(t, NewCompiler(), tc.html)
+		require.EqualError(t, err, "comp.vtpl:4: "+tc.err)
+	}
+}
+
+func TestCompiler_LetElementsOfElementsAreHTML(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<let a={c.a}><let b={c.b}><p>{s:b}</p></let></let>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() *vecty.HTML {
+		a := c.a
+		_ = a
+		return func() *vecty.HTML {
+			b := c.b
+			_ = b
+			return elem.Paragraph(
+				vecty.Text(b),
+			)
+		}()
+	}()
+}`)
+	out, err = compileRender(t, NewCompiler(), `<let a={c.a}>{c.child}</let>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return func() vecty.ComponentOrHTML { a := c.a; _ = a; return c.child }()
+}`)
+}
@@ -0,0 +1,112 @@
+package tvecty
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	modifierNameRegex    = regexp.MustCompile("^[a-z]+$")
+	pipelineStageRegex   = regexp.MustCompile(`^([a-z]+)(?:\((.*)\))?$`)
+	builtinEmbedModifier = map[string]bool{"s": true, "f": true, "d": true, "t": true, unsafeModifier: true}
+)
+
+// Modifier is a user defined embed modifier that calls a Go function with the embedded value, ex. with the modifier
+// {Name: "money", Func: "format.Money"} the embed {money:c.price} or {c.price |> money} becomes format.Money(c.price).
+// Any additional arguments are passed after the value, ex. {truncate:c.name, 20} or {c.name |> truncate(20)} becomes
+// format.Truncate(c.name, 20). The function is expected to return a string, which is wrapped in vecty.Text() when
+// the embed is the content of an element.
+type Modifier struct {
+	Name string `json:"name"`
+	// Func is the function to call, ex. format.Money.
+	Func string `json:"func"`
+	// Import is the import path of the package containing Func, ex. example.com/app/format. It is added to the
+	// imports of any file that uses the modifier.
+	Import string `json:"import"`
+}
+
+// RegisterModifier adds a user defined embed modifier to the compiler.
+func (c *Compiler) RegisterModifier(m Modifier) error {
+	if !modifierNameRegex.MatchString(m.Name) {
+		return fmt.Errorf("invalid modifier name '%s', modifier names must be lower case letters", m.Name)
+	}
+	if builtinEmbedModifier[m.Name] {
+		return fmt.Errorf("invalid modifier name '%s', the name is used by a built in modifier", m.Name)
+	}
+	if m.Func == "" {
+		return fmt.Errorf("modifier '%s' is missing the function to call", m.Name)
+	}
+	c.modifiers[m.Name] = &m
+	return nil
+}
+
+// Checks if an embed is a pipeline, ex. {c.price |> money |> s}, and if so converts it into the equivalent nested
+// modifier calls. Stages are separated by |>, which is not Go, so Go expressions using |, ex. {flags | d}, are never
+// pipelines.
+func parsePipeline(ctx *tagContext, s string, wrapText bool) (expr string, wrapInText, isPipeline bool, err error) {
+	stages := splitPipeline(s)
+	if len(stages) < 2 {
+		return "", false, false, nil
+	}
+	// Converting a stage can move the line to the code of the stage, but the embed continues on the line it started.
+	line := ctx.line
+	defer func() {
+		if err == nil {
+			ctx.line = line
+		}
+	}()
+	expr = strings.TrimSpace(stages[0])
+	for i, stage := range stages[1:] {
+		m := pipelineStageRegex.FindStringSubmatch(strings.TrimSpace(stage))
+		if m == nil {
+			return "", false, true, fmt.Errorf("invalid pipeline stage '%s' in expression: '%s'", strings.TrimSpace(stage), s)
+		}
+		name, args := m[1], m[2]
+		if name == "s" && i != len(stages)-2 {
+			return "", false, true, fmt.Errorf("the s modifier must be the last stage of a pipeline, but was used in '%s'", s)
+		}
+		// Convert the stage into the arguments of the equivalent prefix modifier, ex. t("2006-01-02") is the same as
+		// {t:value, "2006-01-02"}.
+		switch {
+		case name == "f":
+			args = joinArgs(args, expr)
+		case args != "":
+			args = joinArgs(expr, args)
+		default:
+			args = expr
+		}
+		expr, wrapInText, err = parseEmbedModifier(ctx, s, name, args, wrapText)
+		if err != nil {
+			return "", false, true, err
+		}
+	}
+	return expr, wrapInText, true, nil
+}
+
+// Splits an embed into the stages of a pipeline on the top level |>, ex. {c.price |> money |> s}.
+func splitPipeline(s string) []string {
+	var stages []string
+	for i, part := range splitTopLevel(s, '|') {
+		switch {
+		case i == 0:
+			stages = append(stages, part)
+		case strings.HasPrefix(part, ">"):
+			stages = append(stages, part[1:])
+		default:
+			// A | that is Go code, ex. flags | mask or a || b.
+			stages[len(stages)-1] += "|" + part
+		}
+	}
+	return stages
+}
+
+func joinArgs(args ...string) string {
+	var out []string
+	for _, arg := range args {
+		if arg != "" {
+			out = append(out, arg)
+		}
+	}
+	return strings.Join(out, ", ")
+}
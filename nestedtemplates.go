@@ -0,0 +1,147 @@
+package tvecty
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/dave/dst/dstutil"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Matches a template directly followed by another once the templates are replaced, ex. {cond && <p>a</p><p>b</p>}.
+var adjacentTemplatesRegex = regexp.MustCompile("`\\)\\s*tvecty\\.Html\\(")
+
+// Checks if embedded code contains a nested template, ex. {cond && <b/>}.
+func hasNestedTemplate(code string) bool {
+	for i := strings.IndexByte(code, '<'); i >= 0; {
+		if isTemplateStart(code[:i], code[i+1:]) {
+			return true
+		}
+		next := strings.IndexByte(code[i+1:], '<')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+// Parses embedded code containing nested templates, ex. {items.Map(func(i Item) vecty.ComponentOrHTML { return <li/> })},
+// converting the templates the same as templates in the Go source. When the code is a condition and a template, ex.
+// {cond && <b/>}, the template is only rendered when the condition is true, ex. vecty.If(cond, elem.Bold()).
+func parseNestedTemplates(ctx *tagContext, code string, addNewLines bool) (dst.Expr, error) {
+	var b bytes.Buffer
+	tracker, err := sourceHtmlReplace(newHtmlTracker(), &b, bytes.NewReader([]byte(code)))
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parseExpression(b.String(), addNewLines)
+	if err != nil && adjacentTemplatesRegex.MatchString(b.String()) {
+		return nil, fmt.Errorf("adjacent templates in '{%s}' must be wrapped in an element, ex. {cond && <div><p>a</p><p>b</p></div>}", code)
+	}
+	if err != nil {
+		// Report the code as written rather than with the templates replaced.
+		return nil, fmt.Errorf("error with expression '%s':\n%w", code, errors.Unwrap(err))
+	}
+
+	// The lines of the templates are relative to the start of the code.
+	line := ctx.line
+	parsed := make(htmlTrackerParsed, len(tracker)+1)
+	for i, tag := range tracker {
+		offsetLines(tag, line-1)
+		exprs, err := tagToAst(ctx, nil, tag)
+		if err != nil {
+			return nil, err
+		}
+		if len(exprs) == 0 {
+			return nil, fmt.Errorf("the template <%s> in '{%s}' does not render anything", tag.TagName, code)
+		}
+		parsed[i+1] = exprs[0]
+	}
+
+	var cond *dst.BinaryExpr
+	if be, ok := expr.(*dst.BinaryExpr); ok && be.Op == token.LAND {
+		if tmpl, _ := tryConvertTVectyCall(parsed, be.Y); tmpl != nil {
+			cond = be
+		}
+	}
+	var replaceErr error
+	expr = dstutil.Apply(expr, nil, func(c *dstutil.Cursor) bool {
+		e, ok := c.Node().(dst.Expr)
+		if !ok {
+			return true
+		}
+		tmpl, err := tryConvertTVectyCall(parsed, e)
+		if err != nil {
+			replaceErr = err
+			return false
+		}
+		if tmpl != nil {
+			c.Replace(tmpl)
+		}
+		return true
+	}).(dst.Expr)
+	if replaceErr != nil {
+		return nil, replaceErr
+	}
+	if cond != nil {
+		return simpleCallExpr("vecty", "If", []dst.Expr{cond.X, cond.Y}), nil
+	}
+	return expr, nil
+}
+
+// Returns the templates nested in the embedded code of a text tag, ex. <b/> in {cond && <b/>}, or of the attribute
+// values of an element, ex. <li/> in renderItem={func(i Item) vecty.ComponentOrHTML { return <li/> }}, so they can be
+// extracted the same as the other templates in the file.
+func nestedTemplates(tag *html.TagOrText) ([]*html.TagOrText, error) {
+	if tag.TagName == "" {
+		return embeddedTemplates(tag.Text, tag.Line)
+	}
+	var out []*html.TagOrText
+	for _, attr := range tag.Attr {
+		if !hasNestedTemplate(attr.Value) {
+			continue
+		}
+		templates, err := embeddedTemplates(attr.Value, tag.Line)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, templates...)
+	}
+	return out, nil
+}
+
+// Returns the templates nested in the embedded code of text starting at line.
+func embeddedTemplates(text string, startLine int) ([]*html.TagOrText, error) {
+	parts, err := tokenizeExpressionParts(text)
+	if err != nil {
+		return nil, err
+	}
+	var out []*html.TagOrText
+	pos := 0
+	for _, part := range parts {
+		i := strings.Index(text[pos:], part.value)
+		if i < 0 {
+			continue
+		}
+		line := startLine + strings.Count(text[:pos+i], "\n")
+		pos += i + len(part.value)
+		if !part.isEmbeddedCode || !hasNestedTemplate(part.value) {
+			continue
+		}
+		tracker, err := sourceHtmlReplace(newHtmlTracker(), io.Discard, bytes.NewReader([]byte(part.value)))
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tracker {
+			offsetLines(tag, line-1)
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,155 @@
+package tvecty
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_ConvertsConditionalNestedTemplates(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div>{c.ok && <em>it's {c.name}</em>}</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.If(c.ok,
+			elem.Emphasis(
+				vecty.Text("it's"),
+				c.name),
+		),
+	)
+}`)
+}
+
+func TestCompiler_ConvertsTemplatesReturnedFromClosures(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<ul>
+		{c.items.Map(func(i Item) vecty.ComponentOrHTML {
+			return <li class="item">{i.Name}</li>
+		})}
+	</ul>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.UnorderedList(c.items.Map(func(i Item) vecty.ComponentOrHTML {
+		return elem.ListItem(
+			vecty.Markup(
+				vecty.Class("item"),
+			),
+			i.Name)
+	}))
+}`)
+}
+
+func TestCompiler_ConvertsDeeplyNestedTemplates(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div>{c.ok && <p>{c.more && <em>more</em>}</p>}</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.If(c.ok,
+			elem.Paragraph(
+				vecty.If(c.more,
+					elem.Emphasis(
+						vecty.Text("more"),
+					),
+				),
+			),
+		),
+	)
+}`)
+}
+
+func TestCompiler_ReportsTheLineOfErrorsInNestedTemplates(t *testing.T) {
+	_, err := compileRender(t, NewCompiler(), `<div>
+		{c.ok && <p>
+			<em onClick={}>more</em>
+		</p>}
+	</div>`)
+	require.EqualError(t, err, "comp.vtpl:6: event attribute 'onclick' must be a single handler expression, but was '{}'")
+}
+
+func TestCompiler_ConvertsTemplatesNestedInAttributeValues(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(),
+		`<my-list renderItem={func(i Item) vecty.ComponentOrHTML { return <li>{i.Name}</li> }}></my-list>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("my-list",
+		vecty.Markup(
+			vecty.Attribute("renderitem", func(i Item) vecty.ComponentOrHTML {
+				return elem.ListItem(i.Name)
+			}),
+		),
+	)
+}`)
+}
+
+func TestCompiler_ConvertsTemplatesNestedInEventHandlers(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div>
+		<button click={func(e *vecty.Event) { c.show(<p>x</p>) }}>Show</button>
+		<button click="{c.items = append(c.items, <li>new</li>)}">Add</button>
+	</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		elem.Button(
+			vecty.Markup(
+				event.Click(func(e *vecty.Event) {
+					c.show(
+						elem.Paragraph(
+							vecty.Text("x"),
+						),
+					)
+				}),
+			),
+			vecty.Text("Show"),
+		),
+		elem.Button(
+			vecty.Markup(
+				event.Click(func(e *vecty.Event) {
+					c.items = append(c.items,
+						elem.ListItem(
+							vecty.Text("new"),
+						),
+					)
+				}),
+			),
+			vecty.Text("Add"),
+		),
+	)
+}`)
+}
+
+func TestCompiler_AllowsNewLinesAfterOperatorsInNestedTemplates(t *testing.T) {
+	out, err := compileRender(t, NewCompiler(), `<div>{c.ok &&
+		<p>{c.name}</p>}</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.If(c.ok,
+			elem.Paragraph(c.name),
+		),
+	)
+}`)
+}
+
+func TestCompiler_ErrorsOnAdjacentNestedTemplates(t *testing.T) {
+	_, err := compileRender(t, NewCompiler(), `<div>
+		{c.ok && <p>a</p><p>b</p>}
+	</div>`)
+	require.EqualError(t, err, "comp.vtpl:5: adjacent templates in '{c.ok && <p>a</p><p>b</p>}' must be wrapped in an element, "+
+		"ex. {cond && <div><p>a</p><p>b</p></div>}")
+}
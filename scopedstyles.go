@@ -0,0 +1,236 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"hash/fnv"
+	"strings"
+)
+
+// Elements whose content is raw text rather than html, ex. the braces of css are not embeds.
+var rawTextElements = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+// At-rules that contain style rules, so the selectors within them are scoped.
+var nestingAtRules = []string{"@container", "@document", "@layer", "@media", "@supports"}
+
+func isScopedStyle(tag *html.TagOrText) bool {
+	if tag.TagName != "style" {
+		return false
+	}
+	for _, attr := range tag.Attr {
+		if attr.Name == "scoped" {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts the content of a raw text element into a vecty.Text(). Multiline content is written as a raw string so
+// it stays readable.
+func rawTextToAst(existing []dst.Expr, tag *html.TagOrText) []dst.Expr {
+	for _, child := range tag.Children {
+		lit := stringLit(child.Text)
+		if strings.Contains(child.Text, "\n") && !strings.Contains(child.Text, "`") {
+			lit = &dst.BasicLit{Kind: token.STRING, Value: "`" + child.Text + "`"}
+		}
+		existing = append(existing, simpleCallExpr("vecty", "Text", []dst.Expr{lit}))
+	}
+	return existing
+}
+
+// Collects the css of the <style scoped> elements in the templates. The selectors of the css are scoped with a class
+// generated from the css, which is added to every element of the templates. Returns an empty class if there are no
+// scoped styles.
+func collectScopedStyles(filename string, tags []*html.TagOrText) (class string, css string, err error) {
+	var styles []*html.TagOrText
+	for _, tag := range tags {
+		if isScopedStyle(tag) {
+			return "", "", &PositionError{Filename: filename, Line: tag.Line, Err: fmt.Errorf("a <style scoped> element must be inside another element")}
+		}
+		styles = appendScopedStyles(styles, tag)
+	}
+	if len(styles) == 0 {
+		return "", "", nil
+	}
+	var raw []string
+	for _, style := range styles {
+		for _, child := range style.Children {
+			raw = append(raw, child.Text)
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(raw, "\n")))
+	class = fmt.Sprintf("tv-%08x", h.Sum32())
+	var out []string
+	for _, style := range styles {
+		for _, child := range style.Children {
+			scoped, err := scopeCSS(child.Text, class)
+			if err != nil {
+				return "", "", &PositionError{Filename: filename, Line: style.Line, Err: err}
+			}
+			out = append(out, scoped)
+		}
+	}
+	return class, strings.Join(out, "\n\n") + "\n", nil
+}
+
+func appendScopedStyles(styles []*html.TagOrText, tag *html.TagOrText) []*html.TagOrText {
+	if isScopedStyle(tag) {
+		return append(styles, tag)
+	}
+	for _, child := range tag.Children {
+		styles = appendScopedStyles(styles, child)
+	}
+	return styles
+}
+
+// Scopes the selectors of the style rules in the css by adding the class to them, ex. ".btn:hover, p::before {}"
+// becomes ".btn.tv-1234abcd:hover, p.tv-1234abcd::before {}".
+func scopeCSS(css, class string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(css); {
+		end, stop := cssScanUntil(css, i, "{;}")
+		if stop == '}' {
+			return "", fmt.Errorf("unexpected '}' in scoped style")
+		}
+		prelude := css[i:end]
+		if stop == 0 || stop == ';' {
+			// Text after the last rule or a statement, ex. @import "a.css";
+			out.WriteString(css[i:minInt(end+1, len(css))])
+			i = end + 1
+			continue
+		}
+		blockEnd, err := cssMatchingBrace(css, end)
+		if err != nil {
+			return "", err
+		}
+		block := css[end+1 : blockEnd]
+		trimmed := strings.TrimSpace(prelude)
+		leading := prelude[:strings.Index(prelude, trimmed)]
+		switch {
+		case hasAnyPrefix(trimmed, nestingAtRules):
+			inner, err := scopeCSS(block, class)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(prelude + "{" + inner + "}")
+		case strings.HasPrefix(trimmed, "@"):
+			// Other at-rules, ex. @keyframes and @font-face, don't contain selectors.
+			out.WriteString(css[i : blockEnd+1])
+		default:
+			out.WriteString(leading + scopeSelectors(trimmed, class) + " {" + block + "}")
+		}
+		i = blockEnd + 1
+	}
+	return out.String(), nil
+}
+
+// Adds the class to each selector in the list, ex. "a, b > c" becomes "a.tv-x, b > c.tv-x".
+func scopeSelectors(selectors, class string) string {
+	var out []string
+	for _, sel := range splitTopLevel(selectors, ',') {
+		out = append(out, scopeSelector(strings.TrimSpace(sel), "."+class))
+	}
+	return strings.Join(out, ", ")
+}
+
+// Adds the class to the last compound selector, before any pseudo-element since it must come last.
+func scopeSelector(sel, class string) string {
+	compoundStart := 0
+	depth := 0
+	for i, c := range sel {
+		switch c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ' ', '\t', '\n', '>', '+', '~':
+			if depth == 0 {
+				compoundStart = i + 1
+			}
+		}
+	}
+	compound := sel[compoundStart:]
+	insertAt := len(compound)
+	if i := strings.Index(compound, "::"); i >= 0 {
+		insertAt = i
+	} else {
+		for _, legacy := range []string{":before", ":after", ":first-line", ":first-letter"} {
+			if i := strings.Index(compound, legacy); i >= 0 && i < insertAt {
+				insertAt = i
+			}
+		}
+	}
+	return sel[:compoundStart] + compound[:insertAt] + class + compound[insertAt:]
+}
+
+// Gets the index of the first of the stop characters, skipping strings and comments. Returns the length of the css
+// and 0 if none are found.
+func cssScanUntil(css string, start int, stops string) (int, byte) {
+	for i := start; i < len(css); i++ {
+		switch c := css[i]; {
+		case c == '"' || c == '\'':
+			i = cssSkipString(css, i)
+		case c == '/' && strings.HasPrefix(css[i:], "/*"):
+			i = cssSkipComment(css, i)
+		case strings.IndexByte(stops, c) >= 0:
+			return i, c
+		}
+	}
+	return len(css), 0
+}
+
+// Gets the index of the brace closing the brace at start.
+func cssMatchingBrace(css string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(css); i++ {
+		end, c := cssScanUntil(css, i, "{}")
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return end, nil
+			}
+		default:
+			return 0, fmt.Errorf("missing closing '}' in scoped style")
+		}
+		i = end
+	}
+	return 0, fmt.Errorf("missing closing '}' in scoped style")
+}
+
+func cssSkipString(css string, start int) int {
+	for i := start + 1; i < len(css); i++ {
+		switch css[i] {
+		case '\\':
+			i++
+		case css[start]:
+			return i
+		}
+	}
+	return len(css)
+}
+
+func cssSkipComment(css string, start int) int {
+	end := strings.Index(css[start+2:], "*/")
+	if end < 0 {
+		return len(css)
+	}
+	return start + 2 + end + 1
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
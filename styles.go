@@ -0,0 +1,113 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/mdev5000/tvecty/html"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+var stylePropertyRegex = regexp.MustCompile(`^(--[A-Za-z0-9_-]+|-?[A-Za-z][A-Za-z0-9-]*)$`)
+
+// Parse a style attribute into a vecty.Style call for each declaration, ex. style="color: red; margin: 0 {px}" would
+// be vecty.Style("color", "red"), vecty.Style("margin", "0 "+px). A style attribute that is a single embed, ex.
+// style={c.style}, is set as is using vecty.Attribute.
+func parseStyleAttribute(ctx *tagContext, attr *html.Attr) ([]dst.Expr, error) {
+	parts, err := tokenizeExpressionParts(attr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 1 && parts[0].isEmbeddedCode {
+		attrExpr, err := parseSingleAttributeValue(ctx, []dst.Expr{stringLit(attr.Name)}, attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []dst.Expr{simpleCallExpr("vecty", "Attribute", attrExpr)}, nil
+	}
+
+	var out []dst.Expr
+	var problems []string
+	for _, decl := range splitTopLevel(attr.Value, ';') {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+		propAndValue := splitTopLevel(decl, ':')
+		if len(propAndValue) < 2 {
+			problems = append(problems, fmt.Sprintf("malformed declaration '%s', expected 'property: value'", decl))
+			continue
+		}
+		prop := strings.TrimSpace(propAndValue[0])
+		value := strings.TrimSpace(strings.Join(propAndValue[1:], ":"))
+		if !stylePropertyRegex.MatchString(prop) {
+			problems = append(problems, fmt.Sprintf("invalid property '%s' in declaration '%s'", prop, decl))
+			continue
+		}
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("missing value for property '%s'", prop))
+			continue
+		}
+		valueExpr, err := styleValueExpr(value)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		out = append(out, simpleCallExpr("vecty", "Style", []dst.Expr{stringLit(prop), valueExpr}))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid style attribute '%s':\n%s", attr.Value, strings.Join(problems, "\n"))
+	}
+	return out, nil
+}
+
+// Parse a style:property={value} attribute into a vecty.Style call.
+func parseStylePropertyAttribute(attr *html.Attr) (dst.Expr, error) {
+	prop := strings.TrimPrefix(attr.Name, "style:")
+	if strings.HasPrefix(prop, "--") {
+		// Custom properties are case sensitive, ex. --mainColor.
+		prop = attr.RawName[len("style:"):]
+	}
+	if !stylePropertyRegex.MatchString(prop) {
+		return nil, fmt.Errorf("invalid style property '%s' in attribute '%s'", prop, attr.Name)
+	}
+	value := strings.TrimSpace(attr.Value)
+	if value == "" {
+		return nil, fmt.Errorf("missing value for style property attribute '%s'", attr.Name)
+	}
+	valueExpr, err := styleValueExpr(value)
+	if err != nil {
+		return nil, err
+	}
+	return simpleCallExpr("vecty", "Style", []dst.Expr{stringLit(prop), valueExpr}), nil
+}
+
+// Convert a style value into a string expression, concatenating any embeds, ex. 0 {px} -> "0 " + px
+func styleValueExpr(value string) (dst.Expr, error) {
+	parts, err := tokenizeEmbeds(value, false)
+	if err != nil {
+		return nil, err
+	}
+	var out dst.Expr
+	for _, part := range parts {
+		var expr dst.Expr
+		if part.isEmbeddedCode {
+			expr, err = parseExpression(part.value, false)
+			if err != nil {
+				return nil, err
+			}
+			if _, isBinary := expr.(*dst.BinaryExpr); isBinary {
+				expr = &dst.ParenExpr{X: expr}
+			}
+		} else {
+			expr = stringLit(part.value)
+		}
+		if out == nil {
+			out = expr
+		} else {
+			out = &dst.BinaryExpr{X: out, Op: token.ADD, Y: expr}
+		}
+	}
+	return out, nil
+}
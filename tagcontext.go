@@ -0,0 +1,147 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// PositionError is an error converting the html at a position in a file.
+type PositionError struct {
+	Filename string
+	Line     int
+	Err      error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// tagContext holds information about the Go file the html is being converted for.
+type tagContext struct {
+	// Name of the file being converted and the line of the tag currently being converted, for error messages.
+	filename string
+	line     int
+	// Parameter lists of the functions and methods declared in the file, by name.
+	funcs   map[string][]*dst.FieldList
+	methods map[string][]*dst.FieldList
+	// Import paths the generated code requires that the file may not already import.
+	imports map[string]bool
+	// Names the packages imported by the file are referred to by, ex. settings for "example.com/app/settings".
+	packages map[string]bool
+	// Namespace of the element currently being converted, empty for html.
+	namespace string
+	// Custom elements registered with the compiler, by tag name.
+	elements map[string]*CustomElement
+	// The custom element currently being converted, nil if the element is not a registered custom element.
+	element *CustomElement
+	// User defined embed modifiers, by name.
+	modifiers map[string]*Modifier
+	// Whether embeds are wrapped based on their type, and the embeds to wrap, see typeCheckEmbeds.
+	typeCheck bool
+	embeds    []*untypedEmbed
+	// Class added to every element when the templates have scoped styles, see collectScopedStyles.
+	scopeClass string
+	// How static text is translated, nil if it's not.
+	translation *Translation
+	// Whether html comments are left out of the generated code.
+	stripComments bool
+	// Whether {unsafe:...} embeds are reported as errors.
+	disallowUnsafeHTML bool
+	// The files including the file currently being converted, see parseIncludeElement.
+	includes []string
+	// The fills of the layout currently being converted, nil when not converting a layout.
+	slots *layoutSlots
+}
+
+func newTagContext(f *dst.File) *tagContext {
+	ctx := &tagContext{
+		funcs:    map[string][]*dst.FieldList{},
+		methods:  map[string][]*dst.FieldList{},
+		imports:  map[string]bool{},
+		packages: map[string]bool{},
+	}
+	if f == nil {
+		return ctx
+	}
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		ctx.packages[name] = true
+	}
+	for _, d := range f.Decls {
+		fd, ok := d.(*dst.FuncDecl)
+		if !ok {
+			continue
+		}
+		if fd.Recv == nil {
+			ctx.funcs[fd.Name.Name] = append(ctx.funcs[fd.Name.Name], fd.Type.Params)
+		} else {
+			ctx.methods[fd.Name.Name] = append(ctx.methods[fd.Name.Name], fd.Type.Params)
+		}
+	}
+	return ctx
+}
+
+// Determines if expr refers to a function or method declared in the file that takes no arguments, ex. c.Save where
+// the file declares func (c *Comp) Save(). Functions declared outside of the file are never reported as zero-arg.
+func (ctx *tagContext) isZeroArgFunc(expr dst.Expr) bool {
+	var declared []*dst.FieldList
+	switch e := expr.(type) {
+	case *dst.Ident:
+		declared = ctx.funcs[e.Name]
+	case *dst.SelectorExpr:
+		declared = ctx.methods[e.Sel.Name]
+	}
+	if len(declared) == 0 {
+		return false
+	}
+	for _, params := range declared {
+		if params != nil && len(params.List) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var majorVersionRe = regexp.MustCompile(`^v[0-9]+$`)
+
+// Gets the name a package is referred to by when it's imported without a name, ex. settings for
+// "example.com/app/settings" and vecty for "github.com/hexops/vecty/v2".
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionRe.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
+
+// Marks an import as required by the generated code.
+func (ctx *tagContext) requireImport(path string) {
+	ctx.imports[path] = true
+}
+
+// Adds any required imports that are missing from the file.
+func (ctx *tagContext) addImports(f *dst.File) {
+	paths := make([]string, 0, len(ctx.imports))
+	for path := range ctx.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		addImport(f, path)
+	}
+}
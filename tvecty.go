@@ -2,7 +2,6 @@ package tvecty
 
 import (
 	"bytes"
-	"github.com/dave/dst/decorator"
 	"github.com/mdev5000/tvecty/html"
 	"io"
 )
@@ -11,22 +10,7 @@ func ExtractHtml(filename string, w io.Writer, src []byte) ([]*html.TagOrText, e
 	return sourceHtmlReplace(newHtmlTracker(), w, bytes.NewReader(src))
 }
 
+// ConvertToVecty converts the source using a compiler with the default configuration.
 func ConvertToVecty(filename string, w io.Writer, src []byte) error {
-	srcWithoutHtml := bytes.NewBuffer(nil)
-	tracker, err := sourceHtmlReplace(newHtmlTracker(), srcWithoutHtml, bytes.NewReader(src))
-	if err != nil {
-		return err
-	}
-	parsed, err := tracker.parseAll()
-	if err != nil {
-		return err
-	}
-	f, err := decorator.Parse(srcWithoutHtml)
-	if err != nil {
-		return err
-	}
-	if err := Replace(parsed, f); err != nil {
-		return err
-	}
-	return decorator.Fprint(w, f)
+	return NewCompiler().ConvertToVecty(filename, w, src)
 }
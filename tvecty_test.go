@@ -0,0 +1,126 @@
+package tvecty
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConvertToVecty_AdaptsZeroArgEventHandlers(t *testing.T) {
+	in := `package comps
+
+func (c *Comp) Save() {
+}
+
+func (c *Comp) OnKey(e *vecty.Event) {
+}
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div click={c.Save} keyup={c.OnKey}></div>
+}
+`
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, ConvertToVecty("comp.vtpl", out, []byte(in)))
+	requireEqStr(t, out.String(), `
+package comps
+
+func (c *Comp) Save() {
+}
+
+func (c *Comp) OnKey(e *vecty.Event) {
+}
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			event.Click(func(e *vecty.Event) {
+				c.Save()
+			}),
+			event.KeyUp(c.OnKey),
+		),
+	)
+}`)
+}
+
+func TestConvertToVecty_AddsImportsRequiredByBindings(t *testing.T) {
+	in := `package comps
+
+import (
+	"github.com/hexops/vecty"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <input bind:value.int={&c.Age} />
+}
+`
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, ConvertToVecty("comp.vtpl", out, []byte(in)))
+	requireEqStr(t, out.String(), `
+package comps
+
+import (
+	"github.com/hexops/vecty"
+	"strconv"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Input(
+		vecty.Markup(
+			vecty.Property("value", c.Age),
+			event.Input(func(e *vecty.Event) {
+				if v, err := strconv.Atoi(e.Target.Get("value").String()); err == nil {
+					c.Age = v
+				}
+				vecty.Rerender(c)
+			}),
+		),
+	)
+}`)
+}
+
+func TestConvertToVecty_ErrorsOnBindingsToPackageVariables(t *testing.T) {
+	in := `package comps
+
+import (
+	"example.com/app/settings"
+	"github.com/hexops/vecty"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <input bind:value={&settings.Theme} />
+}
+`
+	out := bytes.NewBuffer(nil)
+	err := ConvertToVecty("comp.vtpl", out, []byte(in))
+	require.EqualError(t, err, "comp.vtpl:9: binding 'bind:value' must point to a field of the component so it can be rerendered, ex. {&c.Name}, but was '&settings.Theme'")
+}
+
+func TestConvertToVecty_AddsImportsRequiredByModifiers(t *testing.T) {
+	in := `package comps
+
+import "github.com/hexops/vecty"
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <p title={d:c.count}>{f:"%d of %d", c.done, c.count}</p>
+}
+`
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, ConvertToVecty("comp.vtpl", out, []byte(in)))
+	requireEqStr(t, out.String(), `
+package comps
+
+import (
+	"fmt"
+	"github.com/hexops/vecty"
+	"strconv"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Paragraph(
+		vecty.Markup(
+			vecty.Attribute("title", strconv.FormatInt(int64(c.count), 10)),
+		),
+		vecty.Text(fmt.Sprintf("%d of %d", c.done, c.count)),
+	)
+}`)
+}
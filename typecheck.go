@@ -0,0 +1,300 @@
+package tvecty
+
+import (
+	"fmt"
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const vectyImportPath = "github.com/hexops/vecty"
+
+// How an embed is used, which decides what it's type checked for.
+type embedKind int
+
+const (
+	// Content of an element, ex. {x} in <div>{x}</div>, which is wrapped based on its type.
+	embedChild embedKind = iota
+	// A markup spread, ex. {...c.Markup}, the embed is the vecty.Markup(c.Markup...) call and the spread expression is
+	// passed as is if it's an Applyer.
+	embedSpread
+	// Markup that must be a vecty.Applyer, ex. the value of a conditional attribute.
+	embedApplyer
+)
+
+// An embed whose type is not known without type checking, ex. {x} in <div>{x}</div> without a modifier.
+type untypedEmbed struct {
+	expr dst.Expr
+	src  string
+	line int
+	kind embedKind
+}
+
+// Types from the vecty package used to decide how an embed is rendered.
+type vectyTypes struct {
+	markupOrChild   *types.Interface
+	applyer         *types.Interface
+	componentOrHTML types.Type
+}
+
+// Type checks the package the converted file belongs to and wraps each embed in the content of an element so it can
+// be rendered, ex. a string is wrapped in vecty.Text() and a []vecty.ComponentOrHTML in vecty.List(). The converted
+// file is checked as if it were written to goFilename, next to the other files of the package, so any previous output
+// at that path is replaced rather than checked along with it.
+func typeCheckEmbeds(ctx *tagContext, filename, goFilename string, f *dst.File) error {
+	if len(ctx.embeds) == 0 {
+		return nil
+	}
+	goFile, err := filepath.Abs(goFilename)
+	if err != nil {
+		return err
+	}
+	var restorer *decorator.Restorer
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+			packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir: filepath.Dir(goFile),
+		// The overlay adds the file to the package, but it's never parsed since ParseFile restores the converted file
+		// directly. That way the types can be mapped back to the embeds.
+		Overlay: map[string][]byte{goFile: []byte("package " + f.Name.Name)},
+		ParseFile: func(fset *token.FileSet, name string, src []byte) (*ast.File, error) {
+			if name != goFile {
+				return parser.ParseFile(fset, name, src, parser.AllErrors)
+			}
+			restorer = decorator.NewRestorer()
+			restorer.Fset = fset
+			fr := restorer.FileRestorer()
+			fr.Name = name
+			return fr.RestoreFile(f)
+		},
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return fmt.Errorf("failed to type check '%s': %w", filename, err)
+	}
+	if len(pkgs) != 1 || restorer == nil {
+		return fmt.Errorf("failed to type check '%s', the file is not part of a Go package", filename)
+	}
+	pkg := pkgs[0]
+	// Type errors are expected, since embeds are not wrapped yet, and are only reported for embeds that cannot be
+	// resolved. Any other error means the package could not be checked.
+	var typeErrors []packages.Error
+	for _, e := range pkg.Errors {
+		if e.Kind != packages.TypeError {
+			return fmt.Errorf("failed to type check '%s': %s", filename, e)
+		}
+		typeErrors = append(typeErrors, e)
+	}
+	vectyPkg, ok := pkg.Imports[vectyImportPath]
+	if !ok || vectyPkg.Types == nil {
+		return fmt.Errorf("failed to type check '%s', the package does not import %s", filename, vectyImportPath)
+	}
+	vt := lookupVectyTypes(vectyPkg.Types)
+
+	// Gets the type of an embed, or an error positioned at the embed if it cannot be resolved, ex. an undefined
+	// variable.
+	typeOf := func(embed *untypedEmbed, expr dst.Expr) (types.Type, bool, error) {
+		astExpr, ok := restorer.Ast.Nodes[expr].(ast.Expr)
+		if !ok {
+			return nil, false, nil
+		}
+		if tv, ok := pkg.TypesInfo.Types[astExpr]; ok && isTyped(tv.Type) {
+			return tv.Type, true, nil
+		}
+		start, end := restorer.Fset.Position(astExpr.Pos()), restorer.Fset.Position(astExpr.End())
+		var msgs []string
+		for _, e := range typeErrors {
+			if errorWithin(e, goFile, start, end) {
+				msgs = append(msgs, e.Msg)
+			}
+		}
+		if len(msgs) == 0 {
+			msgs = append(msgs, "the type is unknown")
+		}
+		return nil, false, &PositionError{Filename: filename, Line: embed.line, Err: fmt.Errorf(
+			"cannot resolve the type of embed '{%s}': %s", embed.src, strings.Join(msgs, "; "))}
+	}
+
+	replacements := map[dst.Node]dst.Expr{}
+	for _, embed := range ctx.embeds {
+		switch embed.kind {
+		case embedSpread:
+			spread := embed.expr.(*dst.CallExpr).Args[0]
+			t, ok, err := typeOf(embed, spread)
+			if err != nil {
+				return err
+			}
+			if ok && vt.applyer != nil && types.Implements(t, vt.applyer) {
+				// Keep the spread on its own line like the call it replaces.
+				spread.Decorations().Before = embed.expr.Decorations().Before
+				spread.Decorations().After = embed.expr.Decorations().After
+				replacements[embed.expr] = spread
+			}
+			continue
+		case embedApplyer:
+			t, ok, err := typeOf(embed, embed.expr)
+			if err != nil {
+				return err
+			}
+			if ok && vt.applyer != nil && !types.Implements(t, vt.applyer) {
+				return &PositionError{Filename: filename, Line: embed.line, Err: fmt.Errorf(
+					"markup '{%s}' of type %s is not a vecty.Applyer", embed.src, sourceTypeString(pkg.Types, t))}
+			}
+			continue
+		}
+		t, ok, err := typeOf(embed, embed.expr)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		replacement, err := vt.renderEmbed(pkg.Types, embed, t)
+		if err != nil {
+			return &PositionError{Filename: filename, Line: embed.line, Err: err}
+		}
+		if replacement != nil {
+			replacements[embed.expr] = replacement
+		}
+	}
+	dstutil.Apply(f, nil, func(c *dstutil.Cursor) bool {
+		if expr, ok := replacements[c.Node()]; ok {
+			c.Replace(expr)
+		}
+		return true
+	})
+	return nil
+}
+
+// Checks if a type error is positioned between start and end in file.
+func errorWithin(e packages.Error, file string, start, end token.Position) bool {
+	// The position is file:line:col, the file may contain colons.
+	parts := strings.Split(e.Pos, ":")
+	if len(parts) < 3 {
+		return false
+	}
+	line, lineErr := strconv.Atoi(parts[len(parts)-2])
+	col, colErr := strconv.Atoi(parts[len(parts)-1])
+	if lineErr != nil || colErr != nil || strings.Join(parts[:len(parts)-2], ":") != file {
+		return false
+	}
+	after := func(p token.Position) bool {
+		return line > p.Line || (line == p.Line && col >= p.Column)
+	}
+	return after(start) && !after(end)
+}
+
+// Checks if the type of an expression was resolved.
+func isTyped(t types.Type) bool {
+	return t != nil && t != types.Typ[types.Invalid]
+}
+
+func lookupVectyTypes(pkg *types.Package) *vectyTypes {
+	iface := func(name string) *types.Interface {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil
+		}
+		i, _ := obj.Type().Underlying().(*types.Interface)
+		return i
+	}
+	vt := &vectyTypes{markupOrChild: iface("MarkupOrChild"), applyer: iface("Applyer")}
+	if obj := pkg.Scope().Lookup("ComponentOrHTML"); obj != nil {
+		vt.componentOrHTML = obj.Type()
+	}
+	return vt
+}
+
+// Gets the expression that renders an embed of type t, or nil if the embed can be used as is.
+func (vt *vectyTypes) renderEmbed(pkg *types.Package, embed *untypedEmbed, t types.Type) (dst.Expr, error) {
+	expr := embed.expr
+	cannotRender := func(hint string) error {
+		return fmt.Errorf("embed '{%s}' of type %s cannot be rendered%s", embed.src, sourceTypeString(pkg, t), hint)
+	}
+	if vt.markupOrChild != nil && types.Implements(t, vt.markupOrChild) {
+		return nil, nil
+	}
+	if vt.applyer != nil && types.Implements(t, vt.applyer) {
+		return wrapEmbed(expr, "Markup", nil), nil
+	}
+	if isStringer(t) {
+		return wrapEmbed(expr, "Text", func(x dst.Expr) dst.Expr {
+			return &dst.CallExpr{Fun: &dst.SelectorExpr{X: parenthesize(x), Sel: dst.NewIdent("String")}}
+		}), nil
+	}
+	if basic, ok := t.Underlying().(*types.Basic); ok {
+		switch {
+		case basic.Info()&types.IsString == 0:
+		case types.Identical(t, types.Typ[types.String]) || basic.Info()&types.IsUntyped != 0:
+			return wrapEmbed(expr, "Text", nil), nil
+		default:
+			return wrapEmbed(expr, "Text", func(x dst.Expr) dst.Expr {
+				return &dst.CallExpr{Fun: dst.NewIdent("string"), Args: []dst.Expr{x}}
+			}), nil
+		}
+		if basic.Info()&types.IsNumeric != 0 {
+			return nil, cannotRender(", use a modifier such as {d:...} or {f:...} to format it")
+		}
+	}
+	if slice, ok := t.Underlying().(*types.Slice); ok && vt.componentOrHTML != nil {
+		if types.Identical(slice.Elem(), vt.componentOrHTML) {
+			return wrapEmbed(expr, "List", nil), nil
+		}
+		if types.AssignableTo(slice.Elem(), vt.componentOrHTML) {
+			return nil, cannotRender(", use a []vecty.ComponentOrHTML instead")
+		}
+	}
+	return nil, cannotRender("")
+}
+
+// Qualifies the type by package name as it would be written in the source of pkg, ex. []*vecty.HTML.
+func sourceTypeString(pkg *types.Package, t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	})
+}
+
+// Wraps the embed in a call to a vecty function, ex. vecty.Text(x). If convert is set it's applied to the embed first,
+// ex. vecty.Text(x.String()).
+func wrapEmbed(expr dst.Expr, vectyFn string, convert func(dst.Expr) dst.Expr) dst.Expr {
+	// Remove extra space so it fits nicely on one line.
+	expr.Decorations().Before = dst.SpaceType(0)
+	expr.Decorations().After = dst.SpaceType(0)
+	if convert != nil {
+		expr = convert(expr)
+	}
+	return simpleCallExpr("vecty", vectyFn, []dst.Expr{expr})
+}
+
+func parenthesize(expr dst.Expr) dst.Expr {
+	switch expr.(type) {
+	case *dst.BinaryExpr, *dst.UnaryExpr, *dst.StarExpr:
+		return &dst.ParenExpr{X: expr}
+	}
+	return expr
+}
+
+// Checks if the type has a String() string method.
+func isStringer(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "String")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	return types.Identical(sig.Results().At(0).Type(), types.Typ[types.String])
+}
@@ -0,0 +1,286 @@
+package tvecty
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Creates a module containing a minimal version of vecty, so packages using it can be type checked.
+func typeCheckModule(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	all := map[string]string{
+		"go.mod": `module example.com/app
+
+go 1.16
+
+require github.com/hexops/vecty v0.6.0
+
+replace github.com/hexops/vecty => ./vecty
+`,
+		"vecty/go.mod": "module github.com/hexops/vecty\n\ngo 1.16\n",
+		"vecty/vecty.go": `package vecty
+
+type MarkupOrChild interface{ isMarkupOrChild() }
+
+type ComponentOrHTML interface {
+	isComponentOrHTML()
+	isMarkupOrChild()
+}
+
+type Applyer interface{ Apply(h *HTML) }
+
+type HTML struct{}
+
+func (h *HTML) isMarkupOrChild()    {}
+func (h *HTML) isComponentOrHTML() {}
+
+type List []ComponentOrHTML
+
+func (l List) isMarkupOrChild()    {}
+func (l List) isComponentOrHTML() {}
+
+type MarkupList struct{}
+
+func (m MarkupList) isMarkupOrChild() {}
+func (m MarkupList) Apply(h *HTML)     {}
+
+type markupFunc func(h *HTML)
+
+func (m markupFunc) Apply(h *HTML) {}
+
+func Text(text string) *HTML                { return nil }
+func Markup(m ...Applyer) MarkupList         { return MarkupList{} }
+func Class(class ...string) Applyer          { return markupFunc(nil) }
+func Tag(tag string, m ...MarkupOrChild) *HTML { return nil }
+func If(cond bool, children ...ComponentOrHTML) MarkupOrChild { return nil }
+`,
+		"vecty/elem/elem.go": `package elem
+
+import "github.com/hexops/vecty"
+
+func Div(markup ...vecty.MarkupOrChild) *vecty.HTML { return nil }
+`,
+	}
+	for name, content := range files {
+		all[name] = content
+	}
+	for name, content := range all {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+const typeCheckTypes = `package comps
+
+import "github.com/hexops/vecty"
+
+type Comp struct {
+	Name     string
+	Title    Title
+	Status   Status
+	Count    int
+	Items    []vecty.ComponentOrHTML
+	Divs     []*vecty.HTML
+	Child    *vecty.HTML
+	Classes  vecty.Applyer
+	Markup   []vecty.Applyer
+	Markups  vecty.MarkupList
+	Active   bool
+}
+
+type Title string
+
+type Status int
+
+func (s Status) String() string { return "status" }
+
+func (c *Comp) Size(h *vecty.HTML) int { return c.Count }
+`
+
+func typeCheckCompile(t *testing.T, html string) (string, error) {
+	dir := typeCheckModule(t, map[string]string{"comps/types.go": typeCheckTypes})
+	in := `package comps
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return ` + html + `
+}
+`
+	c := NewCompiler()
+	c.TypeCheck = true
+	out := bytes.NewBuffer(nil)
+	err := c.ConvertToVecty(filepath.Join(dir, "comps", "comp.vtpl"), out, []byte(in))
+	return out.String(), err
+}
+
+// Checks that the converted source of package comps builds against the minimal version of vecty.
+func requireBuilds(t *testing.T, src string) {
+	dir := typeCheckModule(t, map[string]string{"comps/types.go": typeCheckTypes, "comps/comp.go": src})
+	cmd := exec.Command("go", "build", "./comps")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestTypeCheck_WrapsEmbedsBasedOnTheirType(t *testing.T) {
+	out, err := typeCheckCompile(t, `<div>
+		{c.Name}
+		{c.Title}
+		{c.Status}
+		{c.Items}
+		{c.Child}
+		{c.Classes}
+		{s:c.Name}
+		{"literal"}
+	</div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Text(c.Name),
+		vecty.Text(string(c.Title)),
+		vecty.Text(c.Status.String()),
+		vecty.List(c.Items),
+		c.Child,
+		vecty.Markup(c.Classes),
+		vecty.Text(c.Name),
+		vecty.Text("literal"),
+	)
+}`)
+}
+
+func TestTypeCheck_ErrorsWhenAnEmbedCannotBeRendered(t *testing.T) {
+	_, err := typeCheckCompile(t, `<div>
+		{c.Count}
+	</div>`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comp.vtpl:10: embed '{c.Count}' of type int cannot be rendered, use a modifier such as {d:...} or {f:...} to format it")
+
+	_, err = typeCheckCompile(t, `<div>{c.Divs}</div>`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comp.vtpl:9: embed '{c.Divs}' of type []*vecty.HTML cannot be rendered, use a []vecty.ComponentOrHTML instead")
+}
+
+func TestTypeCheck_PassesSpreadApplyersAsIs(t *testing.T) {
+	out, err := typeCheckCompile(t, `<div {...c.Markup} {...c.Markups} {...c.Classes}></div>`)
+	require.NoError(t, err)
+	requireEqStr(t, out, `
+package comps
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Markup(c.Markup...),
+			c.Markups,
+			c.Classes,
+		),
+	)
+}`)
+}
+
+func TestTypeCheck_ErrorsWhenAConditionalValueIsNotAnApplyer(t *testing.T) {
+	out, err := typeCheckCompile(t, `<div markup?="{c.Active, c.Classes}"></div>`)
+	require.NoError(t, err)
+	require.Contains(t, out, "vecty.MarkupIf(c.Active, c.Classes)")
+
+	_, err = typeCheckCompile(t, `<div>
+		<p markup?="{c.Active, c.Name}"></p>
+	</div>`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comp.vtpl:10: markup '{c.Name}' of type string is not a vecty.Applyer")
+}
+
+func TestTypeCheck_PositionsEmbedsContainingTemplatesWhereTheyStart(t *testing.T) {
+	_, err := typeCheckCompile(t, `<div>{c.Size(
+		<div></div>)}</div>`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comp.vtpl:9: embed '{c.Size(")
+}
+
+func TestTypeCheck_ErrorsWhenAnEmbedCannotBeResolved(t *testing.T) {
+	_, err := typeCheckCompile(t, `<div>
+		{c.Missing}
+	</div>`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comp.vtpl:10: cannot resolve the type of embed '{c.Missing}': c.Missing undefined")
+}
+
+func TestTypeCheck_ChecksTheOutputInPlaceOfThePreviousOutput(t *testing.T) {
+	dir := typeCheckModule(t, map[string]string{
+		"comps/types.go": typeCheckTypes,
+		// A previous output that no longer compiles.
+		"comps/comp_gen.go": "package comps\n\nfunc (c *Comp) Render(",
+	})
+	in := []byte(`package comps
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div>{c.Name}</div>
+}
+`)
+	c := NewCompiler()
+	c.TypeCheck = true
+	filename := filepath.Join(dir, "comps", "comp.vtpl")
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, c.ConvertToVectyFile(filename, filepath.Join(dir, "comps", "comp_gen.go"), out, nil, in))
+	require.Contains(t, out.String(), "vecty.Text(c.Name)")
+
+	err := c.ConvertToVecty(filename, bytes.NewBuffer(nil), in)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to type check '"+filename+"'")
+	require.Contains(t, err.Error(), "comp_gen.go:3")
+}
+
+func TestTypeCheck_LetElementsWithConditionalContentBuild(t *testing.T) {
+	out, err := typeCheckCompile(t, `<let name={c.Name}><div/>{c.Active && <div>{s:name}</div>}</let>`)
+	require.NoError(t, err)
+	requireBuilds(t, out)
+
+	out, err = typeCheckCompile(t, `<let name={c.Name}>{c.Active && <div>{s:name}</div>}</let>`)
+	require.NoError(t, err)
+	requireBuilds(t, out)
+}
+
+func TestCompiler_MarkupSpreadsOfSlicesAndMarkupListsBuild(t *testing.T) {
+	dir := t.TempDir()
+	out := bytes.NewBuffer(nil)
+	err := NewCompiler().ConvertToVecty(filepath.Join(dir, "comp.vtpl"), out, []byte(`package comps
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+)
+
+func (c *Comp) Render() vecty.ComponentOrHTML {
+	return <div {...c.Markup} {...c.Markups} {...c.Classes} class="btn"></div>
+}
+`))
+	require.NoError(t, err)
+	requireBuilds(t, out.String())
+}